@@ -0,0 +1,153 @@
+package aggregator
+
+import (
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Separator separates key and its count in counted lines
+const Separator = '\t'
+
+// Format creates counted line of key, count is placed after the last Separator
+func Format(key string, count int64) string {
+	return key + string(Separator) + strconv.FormatInt(count, 10)
+}
+
+// Parse splits counted line into its key and count
+func Parse(line string) (key string, count int64, err error) {
+	i := strings.LastIndexByte(line, Separator)
+	if i < 0 {
+		return "", 0, fmt.Errorf("no count in line %q", line)
+	}
+	count, err = strconv.ParseInt(line[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid count in line %q: %v", line, err)
+	}
+	return line[:i], count, nil
+}
+
+// Comparator creates comparator which orders counted lines by their keys with cmp
+func Comparator(cmp *comparator.Comparator) *comparator.Comparator {
+	return comparator.New(cmp.Name+"-counted", func(line string) string {
+		if i := strings.LastIndexByte(line, Separator); i >= 0 {
+			line = line[:i]
+		}
+		return cmp.Key(line)
+	})
+}
+
+// Writer writes counted lines of sorted keys, counts of consecutive equal keys are summed up
+// Keys are equal if their normalized keys by the comparator are equal, the first one is written
+type Writer struct {
+	w       *bufio.Writer
+	cmp     *comparator.Comparator
+	key     string // current key
+	normKey string // normalized current key
+	count   int64  // sum of counts of current key
+	hasKey  bool
+}
+
+// NewWriter creates Writer of counted lines into w, keys should be added in order of cmp
+func NewWriter(w io.Writer, cmp *comparator.Comparator) *Writer {
+	return &Writer{w: bufio.NewWriter(w), cmp: cmp}
+}
+
+// Add adds count of key, keys should be added in order
+func (a *Writer) Add(key string, count int64) error {
+	normKey := a.cmp.Key(key)
+	if a.hasKey && normKey == a.normKey {
+		a.count += count
+		return nil
+	}
+
+	err := a.writeCurrent()
+	if err != nil {
+		return err
+	}
+	a.key, a.normKey, a.count, a.hasKey = key, normKey, count, true
+	return nil
+}
+
+// AddLine adds counted line
+func (a *Writer) AddLine(line string) error {
+	key, count, err := Parse(line)
+	if err != nil {
+		return err
+	}
+	return a.Add(key, count)
+}
+
+func (a *Writer) writeCurrent() error {
+	if !a.hasKey {
+		return nil
+	}
+	_, err := a.w.WriteString(Format(a.key, a.count))
+	if err == nil {
+		err = a.w.WriteByte('\n')
+	}
+	return err
+}
+
+// Flush writes the last key and flushes underlying buffer
+func (a *Writer) Flush() error {
+	err := a.writeCurrent()
+	if err != nil {
+		return err
+	}
+	a.hasKey = false
+	return a.w.Flush()
+}
+
+// Count converts sorted lines of ch into counted lines in batches, equal keys are counted in one line
+func Count(ctx context.Context, ch <-chan []string, cmp *comparator.Comparator) <-chan []string {
+	out := make(chan []string)
+
+	go func() {
+		defer close(out)
+
+		batch := make([]string, 0, helper.BatchSize)
+		send := func() bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- batch:
+				batch = make([]string, 0, helper.BatchSize)
+				return true
+			}
+		}
+
+		var key, normKey string
+		var count int64
+		for lines := range ch {
+			for _, line := range lines {
+				lineKey := cmp.Key(line)
+				if count > 0 && lineKey == normKey {
+					count++
+					continue
+				}
+				if count > 0 {
+					batch = append(batch, Format(key, count))
+					if len(batch) == helper.BatchSize && !send() {
+						return
+					}
+				}
+				key, normKey, count = line, lineKey, 1
+			}
+		}
+
+		if count > 0 {
+			batch = append(batch, Format(key, count))
+		}
+		if len(batch) > 0 {
+			send()
+		}
+	}()
+
+	return out
+}
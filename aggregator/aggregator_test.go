@@ -0,0 +1,79 @@
+package aggregator
+
+import (
+	"AID/solution/comparator"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	key, count, err := Parse(Format("key\twith tab", 42))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if key != "key\twith tab" || count != 42 {
+		t.Errorf("parsed %q %d", key, count)
+	}
+
+	for _, line := range []string{"no count", "key\tNaN"} {
+		_, _, err = Parse(line)
+		if err == nil {
+			t.Errorf("expected error of parsing %q", line)
+		}
+	}
+}
+
+func TestComparator(t *testing.T) {
+	cmp := Comparator(comparator.CaseFold)
+	// Counts don't take part in order
+	if !cmp.Less(Format("a", 9), Format("B", 1)) {
+		t.Error("a should be less than B")
+	}
+	if cmp.Less(Format("A", 1), Format("a", 9)) || cmp.Less(Format("a", 9), Format("A", 1)) {
+		t.Error("A and a should be equal")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer, comparator.CaseFold)
+	for _, line := range []string{"Apple\t2", "apple\t3", "banana\t1", "cherry\t4", "CHERRY\t1"} {
+		err := w.AddLine(line)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	err := w.Flush()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "Apple\t5\nbanana\t1\ncherry\t5\n"
+	if buffer.String() != expected {
+		t.Errorf("written %q, expected %q", buffer.String(), expected)
+	}
+}
+
+func TestCount(t *testing.T) {
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		ch <- []string{"a", "a", "b"}
+		ch <- []string{"b", "c"}
+	}()
+
+	var result []string
+	for batch := range Count(context.Background(), ch, comparator.Bytewise) {
+		result = append(result, batch...)
+	}
+
+	expected := []string{"a\t2", "b\t2", "c\t1"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("counted %v, expected %v", result, expected)
+	}
+}
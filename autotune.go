@@ -0,0 +1,255 @@
+package main
+
+import (
+	"AID/solution/helper"
+	"AID/solution/sorter"
+	"AID/solution/sysinfo"
+	"AID/solution/tempstorage"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var autoTuneFlag = flag.Bool("auto", false, "choose k, n, chan-buf, p, r and partitions by system resources and input, explicit settings are kept")
+
+const (
+	lineOverhead      = 32      // bytes of string header and slice slot of each line in memory
+	reservedFiles     = 64      // open files reserved for input, output, logs and runtime
+	maxChanBuf        = 16      // largest derived size of channel buffers in batches
+	maxReaders        = 4       // more concurrent readers of input files mostly cause seeks
+	defaultLineLength = 64      // average line length if input cannot be sampled
+	lineSampleSize    = 1 << 20 // bytes of input sampled to find average line length
+	defaultMemory     = 1 << 30 // available memory if it cannot be read
+	defaultOpenFiles  = 1024    // limit of open files if it cannot be read
+	maxOpenFiles      = 1 << 20 // limit of open files tuning is based on, higher limits are capped to it
+)
+
+// channelBufferSize returns size of channel buffers in batches, k lines in memory are shared by n merged files
+func channelBufferSize(k, n int) int {
+	size := k / (n * helper.BatchSize)
+	if size < 1 {
+		return 1
+	}
+	if size > maxChanBuf {
+		return maxChanBuf
+	}
+	return size
+}
+
+// fitIOMemory derives I/O block size of cfg from memoryBudget bytes
+// If buffers of merged files don't fit in the budget by blocks of minimum size, fan-in is reduced,
+// or read-ahead is disabled if even two files don't fit
+func fitIOMemory(cfg *sorter.Config, memoryBudget int64) {
+	maxFanIn := tempstorage.MaxFanInFromBudget(memoryBudget)
+	if cfg.FanIn() > maxFanIn {
+		if maxFanIn < 2 {
+			log.Warningf("I/O memory of %d bytes doesn't fit buffers of two files of %d KB blocks, read-ahead is disabled",
+				memoryBudget, tempstorage.MinIOBlockSize>>10)
+			return
+		}
+		log.Warningf("Fan-in is reduced from %d to %d to fit buffers of %d KB blocks in %d MB I/O memory",
+			cfg.FanIn(), maxFanIn, tempstorage.MinIOBlockSize>>10, memoryBudget>>20)
+		cfg.N = maxFanIn
+	}
+	cfg.IOBlockSize = tempstorage.IOBlockSizeFromBudget(memoryBudget, cfg.FanIn())
+}
+
+// resources are system resources and input properties tuning is based on
+type resources struct {
+	memory        uint64  // available memory in bytes
+	openFiles     uint64  // limit of open files
+	cpus          int     // number of CPUs
+	ioMemory      int64   // memory budget of temporary files buffers in bytes
+	inputSize     int64   // total size of input files in bytes
+	avgLineLength float64 // average length of input lines in bytes
+	incremental   bool    // sort is incremental, which cannot be partitioned
+}
+
+// tuning is settings chosen by tune, with the reason of each one
+type tuning struct {
+	values  map[string]int
+	reasons map[string]string
+}
+
+func (t *tuning) set(name string, value int, reason string, args ...interface{}) {
+	t.values[name] = value
+	t.reasons[name] = fmt.Sprintf(reason, args...)
+}
+
+// tune chooses settings by resources
+func tune(r resources) tuning {
+	t := tuning{values: make(map[string]int), reasons: make(map[string]string)}
+
+	readers := r.cpus
+	if readers > maxReaders {
+		readers = maxReaders
+	}
+	t.set("p", r.cpus, "%d CPUs", r.cpus)
+	t.set("r", readers, "%d CPUs, at most %d readers to limit seeks", r.cpus, maxReaders)
+
+	// Half of memory is for two bundles in memory, one is sorted while the other one is stored,
+	// the rest is for I/O buffers, channels and runtime
+	lineCost := r.avgLineLength + lineOverhead
+	k := int(float64(r.memory/2) / (2 * lineCost))
+	kReason := fmt.Sprintf("%d MB available memory, half of it for two bundles of %.0f bytes per line (%.0f bytes average line length)",
+		r.memory>>20, lineCost, r.avgLineLength)
+	inputLines := int(float64(r.inputSize) / r.avgLineLength)
+	if inputLines < k {
+		k = inputLines
+		kReason = fmt.Sprintf("input has about %d lines", inputLines)
+	}
+	if k < 2 {
+		k = 2
+	}
+	t.set("k", k, kReason)
+
+	openFiles := r.openFiles
+	if openFiles > maxOpenFiles {
+		// Limit may be unlimited (RLIM_INFINITY), which doesn't fit in int
+		openFiles = maxOpenFiles
+	}
+	n := int(openFiles) - reservedFiles - readers
+	nReason := fmt.Sprintf("%d open files limit, %d reserved and %d for readers", openFiles, reservedFiles, readers)
+	if r.ioMemory > 0 {
+		// Each merged file has two read-ahead blocks and the stored file has one
+		maxByIO := tempstorage.MaxFanInFromBudget(r.ioMemory)
+		if maxByIO < n {
+			n = maxByIO
+			nReason = fmt.Sprintf("%d MB I/O memory fits buffers of %d files of %d KB blocks", r.ioMemory>>20, n, tempstorage.MinIOBlockSize>>10)
+		}
+	}
+	if n < 2 {
+		n = 2
+	}
+	t.set("n", n, nReason)
+
+	t.set("chan-buf", channelBufferSize(k, n), "%d lines in memory shared by %d merged files in batches of %d lines", k, n, helper.BatchSize)
+
+	runs := (inputLines + k - 1) / k
+	partitions := r.cpus
+	partitionsReason := fmt.Sprintf("input needs about %d runs, key ranges are sorted in parallel by %d CPUs", runs, r.cpus)
+	if runs < partitions {
+		partitions = runs
+	}
+	// Bundles, open files and I/O memory are divided between partitions, see sorter.PartitionedSort
+	if maxByFiles := n / 2; maxByFiles < partitions {
+		partitions = maxByFiles
+		partitionsReason = fmt.Sprintf("%d open files and their I/O memory are divided between partitions which merge at least 2 files each", n)
+	}
+	if r.incremental {
+		partitions = 1
+		partitionsReason = "incremental sort cannot be partitioned"
+	}
+	if partitions < 1 {
+		partitions = 1
+	}
+	t.set("partitions", partitions, partitionsReason)
+
+	return t
+}
+
+// sampleLineLength returns average line length of first sampleSize bytes of files
+func sampleLineLength(files []string, sampleSize int64) (float64, error) {
+	var size, lines int64
+	buffer := make([]byte, 64<<10)
+	for _, path := range files {
+		if size >= sampleSize {
+			break
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		reader := io.LimitReader(file, sampleSize-size)
+		for {
+			var read int
+			read, err = reader.Read(buffer)
+			size += int64(read)
+			lines += int64(bytes.Count(buffer[:read], []byte{'\n'}))
+			if err != nil {
+				break
+			}
+		}
+		_ = file.Close()
+		if err != io.EOF {
+			return 0, err
+		}
+	}
+
+	if lines == 0 {
+		return float64(size), nil
+	}
+	return float64(size) / float64(lines), nil
+}
+
+// gatherResources reads system resources and samples input, unavailable ones are replaced by defaults
+func gatherResources() resources {
+	r := resources{
+		cpus:          runtime.NumCPU(),
+		ioMemory:      *ioMemory << 20,
+		avgLineLength: defaultLineLength,
+		incremental:   *incremental,
+	}
+
+	memory, err := getMemoryLimit()
+	if err != nil {
+		log.Warningf("Unable to read available memory, %d MB is assumed: %v", defaultMemory>>20, err)
+		memory = defaultMemory
+	}
+	r.memory = memory
+
+	r.openFiles, err = sysinfo.OpenFileLimit()
+	if err != nil {
+		log.Warningf("Unable to read open files limit, %d is assumed: %v", defaultOpenFiles, err)
+		r.openFiles = defaultOpenFiles
+	}
+
+	dirSerializer, err := newDirSerializer()
+	if err == nil {
+		var files []string
+		files, err = dirSerializer.Files()
+		if err == nil {
+			r.inputSize, err = dirSerializer.InputSize()
+		}
+		if err == nil && r.inputSize > 0 {
+			r.avgLineLength, err = sampleLineLength(files, lineSampleSize)
+		}
+	}
+	if err != nil {
+		log.Warningf("Unable to sample input, average line length of %d bytes is assumed: %v", defaultLineLength, err)
+		r.avgLineLength = defaultLineLength
+	}
+	if r.avgLineLength < 1 {
+		r.avgLineLength = 1
+	}
+
+	return r
+}
+
+// autoTune sets flags of fs by system resources and input, flags set by user are kept
+func autoTune(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	t := tune(gatherResources())
+	for _, name := range []string{"p", "r", "k", "n", "chan-buf", "partitions"} {
+		if explicit[name] {
+			log.Infof("Auto tune keeps %s=%s given explicitly", name, fs.Lookup(name).Value.String())
+			continue
+		}
+		err := fs.Set(name, strconv.Itoa(t.values[name]))
+		if err != nil {
+			return err
+		}
+		log.Infof("Auto tune sets %s=%d: %s", name, t.values[name], t.reasons[name])
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"AID/solution/sorter"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChannelBufferSize(t *testing.T) {
+	tests := []struct{ k, n, expected int }{
+		{4, 5000, 1},
+		{10000000, 5000, 1},
+		{100000000, 5000, maxChanBuf},
+		{10000000, 100, 16},
+		{1000000, 100, 9},
+	}
+	for _, test := range tests {
+		if size := channelBufferSize(test.k, test.n); size != test.expected {
+			t.Errorf("channel buffer size of k %d and n %d is %d, but should be %d", test.k, test.n, size, test.expected)
+		}
+	}
+}
+
+func TestFitIOMemory(t *testing.T) {
+	tests := []struct {
+		k, n          int
+		memoryBudget  int64
+		expectedN     int
+		expectedBlock int
+	}{
+		{100000, 5000, 64 << 20, 511, (64 << 20) / 1023}, // fan-in is reduced
+		{4, 5000, 64 << 20, 5000, (64 << 20) / 9},        // fan-in fits
+		{100000, 5000, 256 << 10, 5000, 0},               // read-ahead is disabled
+	}
+	for _, test := range tests {
+		cfg := sorter.Config{K: test.k, N: test.n}
+		fitIOMemory(&cfg, test.memoryBudget)
+		if cfg.N != test.expectedN || cfg.IOBlockSize != test.expectedBlock {
+			t.Errorf("n and I/O block size of k %d, n %d and %d bytes I/O memory are %d and %d, but should be %d and %d",
+				test.k, test.n, test.memoryBudget, cfg.N, cfg.IOBlockSize, test.expectedN, test.expectedBlock)
+		}
+	}
+}
+
+func TestTune(t *testing.T) {
+	big := tune(resources{
+		memory:        8 << 30,
+		openFiles:     1024,
+		cpus:          8,
+		ioMemory:      1 << 30,
+		inputSize:     1 << 40,
+		avgLineLength: 32,
+	})
+	// 4 GB for two bundles of 64 bytes per line
+	if k := big.values["k"]; k != 1<<25 {
+		t.Errorf("k is %d", k)
+	}
+	if n := big.values["n"]; n != 1024-reservedFiles-maxReaders {
+		t.Errorf("n is %d", n)
+	}
+	if big.values["partitions"] != 8 || big.values["r"] != maxReaders || big.values["p"] != 8 {
+		t.Errorf("workers are %v", big.values)
+	}
+	if size := big.values["chan-buf"]; size < 1 || size > maxChanBuf {
+		t.Errorf("chan-buf is %d", size)
+	}
+	for name := range big.values {
+		if big.reasons[name] == "" {
+			t.Errorf("no reason for %s", name)
+		}
+	}
+
+	small := tune(resources{
+		memory:        8 << 30,
+		openFiles:     1 << 20,
+		cpus:          2,
+		ioMemory:      64 << 20,
+		inputSize:     1000,
+		avgLineLength: 10,
+	})
+	// Bundles are not bigger than input and I/O buffers limit fan-in
+	if k := small.values["k"]; k != 100 {
+		t.Errorf("k of small input is %d", k)
+	}
+	if n := small.values["n"]; n != 511 {
+		t.Errorf("n limited by I/O memory is %d", n)
+	}
+	if small.values["partitions"] != 1 {
+		t.Errorf("small input should not be partitioned")
+	}
+
+	unlimited := tune(resources{
+		memory:        8 << 30,
+		openFiles:     math.MaxUint64, // RLIM_INFINITY
+		cpus:          2,
+		inputSize:     1 << 40,
+		avgLineLength: 32,
+	})
+	if n := unlimited.values["n"]; n != maxOpenFiles-reservedFiles-2 {
+		t.Errorf("n of unlimited open files is %d", n)
+	}
+
+	incremental := tune(resources{
+		memory:        8 << 30,
+		openFiles:     1024,
+		cpus:          8,
+		inputSize:     1 << 40,
+		avgLineLength: 32,
+		incremental:   true,
+	})
+	if incremental.values["partitions"] != 1 {
+		t.Errorf("incremental sort should not be partitioned")
+	}
+
+	// 7 files fit in 1 MB I/O memory, each partition merges at least 2 of them
+	fewFiles := tune(resources{
+		memory:        8 << 30,
+		openFiles:     1024,
+		cpus:          8,
+		ioMemory:      1 << 20,
+		inputSize:     1 << 40,
+		avgLineLength: 32,
+	})
+	if n, partitions := fewFiles.values["n"], fewFiles.values["partitions"]; n != 7 || partitions != 3 {
+		t.Errorf("n and partitions limited by I/O memory are %d and %d", n, partitions)
+	}
+}
+
+func TestSampleLineLength(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	first, second := filepath.Join(dir, "1"), filepath.Join(dir, "2")
+	if err = ioutil.WriteFile(first, []byte("abc\nabcdefg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(second, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	length, err := sampleLineLength([]string{first, second}, 1<<20)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if math.Abs(length-14.0/3) > 1e-9 {
+		t.Errorf("average line length is %f", length)
+	}
+
+	// Only first bytes are sampled
+	length, err = sampleLineLength([]string{first, second}, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if length != 4 {
+		t.Errorf("average line length of sample is %f", length)
+	}
+}
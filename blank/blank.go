@@ -0,0 +1,145 @@
+// Package blank defines policies of blank lines, which are empty or white space only,
+// and padded lines, which have leading or trailing white space, e.g. "beer  "
+// A Filter of policy is applied the same way by input serializers, bundler and output
+package blank
+
+import (
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy defines how blank and padded lines are handled
+type Policy int
+
+const (
+	// Keep keeps lines as they are
+	Keep Policy = iota
+	// Drop drops blank lines
+	Drop
+	// Trim compares lines without their leading and trailing white space, but keeps them as they are,
+	// so padded lines are next to their trimmed variants and blank lines are first
+	Trim
+	// Count keeps lines as they are, but counts blank and padded lines to be reported
+	Count
+)
+
+var policyNames = []string{"keep", "drop", "trim", "count"}
+
+func (p Policy) String() string {
+	if p < 0 || int(p) >= len(policyNames) {
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+	return policyNames[p]
+}
+
+// GetPolicy returns policy by name: keep, drop, trim or count
+func GetPolicy(name string) (Policy, error) {
+	for i, policyName := range policyNames {
+		if name == policyName {
+			return Policy(i), nil
+		}
+	}
+	return Keep, fmt.Errorf("unknown blank lines policy %s", name)
+}
+
+// IsBlank checks whether line is empty or white space only
+func IsBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// IsPadded checks whether line which is not blank has leading or trailing white space
+func IsPadded(line string) bool {
+	if line == "" {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(line)
+	last, _ := utf8.DecodeLastRuneInString(line)
+	return unicode.IsSpace(first) || unicode.IsSpace(last)
+}
+
+// Filter applies policy to lines and counts lines it drops or is asked to count,
+// it is safe for concurrent use
+type Filter struct {
+	policy Policy
+	blank  int64 // number of dropped or counted blank lines
+	padded int64 // number of counted padded lines
+}
+
+// NewFilter creates Filter of policy
+func NewFilter(policy Policy) *Filter {
+	return &Filter{policy: policy}
+}
+
+// Policy returns policy of f
+func (f *Filter) Policy() Policy {
+	return f.policy
+}
+
+// Keep checks whether line is kept by policy, blank lines are dropped by Drop policy
+// Dropped lines and lines counted by Count policy are counted
+func (f *Filter) Keep(line string) bool {
+	switch f.policy {
+	case Drop:
+		if IsBlank(line) {
+			atomic.AddInt64(&f.blank, 1)
+			return false
+		}
+	case Count:
+		if IsBlank(line) {
+			atomic.AddInt64(&f.blank, 1)
+		} else if IsPadded(line) {
+			atomic.AddInt64(&f.padded, 1)
+		}
+	}
+	return true
+}
+
+// Transform returns bundler transform of policy, which drops blank lines made by earlier transforms,
+// nil if policy drops nothing
+// Lines are counted only once, when they are read, so Count policy has no transform
+func (f *Filter) Transform() bundler.TransformFunc {
+	if f.policy != Drop {
+		return nil
+	}
+	return bundler.DropTransform(func(line string) bool {
+		return !f.Keep(line)
+	})
+}
+
+// Comparator returns cmp which compares lines without their leading and trailing white space
+// by Trim policy, otherwise cmp as is
+func (f *Filter) Comparator(cmp *comparator.Comparator) *comparator.Comparator {
+	if f.policy != Trim {
+		return cmp
+	}
+	return comparator.New(cmp.Name+"-trimmed", func(s string) string {
+		return cmp.Key(strings.TrimSpace(s))
+	})
+}
+
+// Blank returns number of dropped or counted blank lines
+func (f *Filter) Blank() int64 {
+	return atomic.LoadInt64(&f.blank)
+}
+
+// Padded returns number of counted padded lines
+func (f *Filter) Padded() int64 {
+	return atomic.LoadInt64(&f.padded)
+}
+
+// Report logs numbers of dropped or counted lines
+func (f *Filter) Report() {
+	switch f.policy {
+	case Drop:
+		log.Infof("Dropped %d blank lines", f.Blank())
+	case Count:
+		log.Infof("Found %d blank lines and %d lines with leading or trailing white space", f.Blank(), f.Padded())
+	}
+}
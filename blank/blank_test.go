@@ -0,0 +1,81 @@
+package blank
+
+import (
+	"AID/solution/comparator"
+	"reflect"
+	"testing"
+)
+
+func TestGetPolicy(t *testing.T) {
+	for _, p := range []Policy{Keep, Drop, Trim, Count} {
+		policy, err := GetPolicy(p.String())
+		if err != nil || policy != p {
+			t.Errorf("policy of %s is %v, error %v", p, policy, err)
+		}
+	}
+
+	_, err := GetPolicy("skip")
+	if err == nil {
+		t.Error("unknown policy should return error")
+	}
+}
+
+func TestIsBlank(t *testing.T) {
+	for _, line := range []string{"", " ", "\t \r", " "} {
+		if !IsBlank(line) {
+			t.Errorf("%q should be blank", line)
+		}
+	}
+	for _, line := range []string{"beer", " beer "} {
+		if IsBlank(line) {
+			t.Errorf("%q should not be blank", line)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	lines := []string{"", "beer  ", " ", "beer", "\tbeer"}
+
+	var kept []string
+	f := NewFilter(Count)
+	for _, line := range lines {
+		if f.Keep(line) {
+			kept = append(kept, line)
+		}
+	}
+	if !reflect.DeepEqual(kept, lines) {
+		t.Errorf("count policy kept %q", kept)
+	}
+	if f.Blank() != 2 || f.Padded() != 2 {
+		t.Errorf("count policy counted %d blank and %d padded lines", f.Blank(), f.Padded())
+	}
+	if NewFilter(Count).Transform() != nil {
+		t.Error("count policy should have no transform")
+	}
+
+	f = NewFilter(Drop)
+	input := make([]string, len(lines))
+	copy(input, lines)
+	result := f.Transform()(input)
+	expected := []string{"beer  ", "beer", "\tbeer"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("drop policy transform result is %q, but should be %q", result, expected)
+	}
+	if f.Blank() != 2 {
+		t.Errorf("drop policy dropped %d blank lines", f.Blank())
+	}
+}
+
+func TestFilter_Comparator(t *testing.T) {
+	cmp := NewFilter(Trim).Comparator(comparator.Bytewise)
+	if cmp.Less("beer  ", "beer") || cmp.Less("beer", "beer  ") {
+		t.Error("beer and padded beer should be equal by trim policy")
+	}
+	if !cmp.Less(" ", "apple") || !cmp.Less(" apple", "beer") {
+		t.Error("lines should be compared without leading white space")
+	}
+
+	if NewFilter(Drop).Comparator(comparator.Bytewise) != comparator.Bytewise {
+		t.Error("drop policy should keep comparator")
+	}
+}
@@ -7,22 +7,61 @@ import (
 // Bundler bundler entity
 type Bundler interface {
 	AddTransformFunc(f TransformFunc)
-	GetBundlerCh(context.Context, <-chan string) <-chan []string
+	SetSpillFunc(f SpillFunc)
+	GetBundlerCh(context.Context, <-chan []string) <-chan []string
 }
 
-// TransformFunc define transform logic
-type TransformFunc = func([]string)
+// TransformFunc define transform logic, it returns transformed bundle which may be
+// the input modified in place, a filtered or an expanded one
+type TransformFunc = func([]string) []string
+
+// SpillFunc checks whether a bundle should be sent before it is full, e.g. when memory is under pressure
+type SpillFunc = func() bool
+
+// minSpillDivisor is the ratio of bundle size to the least size of a spilled bundle,
+// so pressure doesn't make many tiny runs which need more merge levels
+const minSpillDivisor = 8
+
+// shouldSpill checks whether a bundle of size lines, which is not full, is sent by spill
+func shouldSpill(spill SpillFunc, size, k int) bool {
+	return spill != nil && size >= k/minSpillDivisor && spill()
+}
 
 type bundler struct {
 	k          int // bundler size
 	transforms []TransformFunc
+	spill      SpillFunc // checked after each batch once bundle has k/minSpillDivisor lines, nil means bundles are always full
 }
 
 func (b *bundler) AddTransformFunc(f TransformFunc) {
 	b.transforms = append(b.transforms, f)
 }
 
-func (b *bundler) GetBundlerCh(ctx context.Context, inCh <-chan string) <-chan []string {
+// SetSpillFunc sets f to check whether current bundle should be sent before it is full,
+// so its memory can be freed sooner
+func (b *bundler) SetSpillFunc(f SpillFunc) {
+	b.spill = f
+}
+
+// transform applies transforms to bundle in order
+func (b *bundler) transform(bundle []string) []string {
+	for _, t := range b.transforms {
+		bundle = t(bundle)
+	}
+	return bundle
+}
+
+// send puts bundle in ch unless ctx is done first, returns whether bundle is sent
+func send(ctx context.Context, ch chan<- []string, bundle []string) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- bundle:
+		return true
+	}
+}
+
+func (b *bundler) GetBundlerCh(ctx context.Context, inCh <-chan []string) <-chan []string {
 	ch := make(chan []string)
 
 	go func() {
@@ -32,21 +71,31 @@ func (b *bundler) GetBundlerCh(ctx context.Context, inCh <-chan string) <-chan [
 			select {
 			case <-ctx.Done():
 				return
-			case s, ok := <-inCh:
+			case batch, ok := <-inCh:
 				if ok {
-					bundle = append(bundle, s)
-					if len(bundle) == b.k {
-						for _, t := range b.transforms {
-							t(bundle)
+					for len(batch) > 0 {
+						// Fill bundle up to k with head of batch
+						n := b.k - len(bundle)
+						if n > len(batch) {
+							n = len(batch)
+						}
+						bundle = append(bundle, batch[:n]...)
+						batch = batch[n:]
+
+						if len(bundle) == b.k || (len(batch) == 0 && shouldSpill(b.spill, len(bundle), b.k)) {
+							bundle = b.transform(bundle)
+							// Bundles whose lines are all dropped are not sent
+							if len(bundle) > 0 && !send(ctx, ch, bundle) {
+								return
+							}
+							bundle = make([]string, 0, b.k)
 						}
-						ch <- bundle
-						bundle = make([]string, 0, b.k)
 					}
 				} else {
-					for _, t := range b.transforms {
-						t(bundle)
+					bundle = b.transform(bundle)
+					if len(bundle) > 0 {
+						send(ctx, ch, bundle)
 					}
-					ch <- bundle
 					return
 				}
 			}
@@ -59,7 +108,7 @@ func (b *bundler) GetBundlerCh(ctx context.Context, inCh <-chan string) <-chan [
 // GetNewBundler creates new bundler entity which creates bundles of size k
 func GetNewBundler(k int) Bundler {
 	return &bundler{
-		k,
-		[]TransformFunc{},
+		k:          k,
+		transforms: []TransformFunc{},
 	}
 }
@@ -2,7 +2,9 @@ package bundler
 
 import (
 	"context"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -15,7 +17,7 @@ func runBundler(t *testing.T, k int, sampleInput []string, validator bundleValid
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
 
-	inputCh := make(chan string)
+	inputCh := make(chan []string)
 	bundler := GetNewBundler(k)
 	for _, t := range transforms {
 		bundler.AddTransformFunc(t)
@@ -26,15 +28,21 @@ func runBundler(t *testing.T, k int, sampleInput []string, validator bundleValid
 		sampleInput = []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "eleven", "twelve"}
 	}
 
+	// Batch size is chosen to not be aligned with bundle sizes
+	batchSize := 3
 	go func() {
 		defer close(inputCh)
-		for _, s := range sampleInput {
+		for i := 0; i < len(sampleInput); i += batchSize {
+			end := i + batchSize
+			if end > len(sampleInput) {
+				end = len(sampleInput)
+			}
 			select {
 			case <-ctx.Done():
 				t.Error("Timed out")
 				return
-			case inputCh <- s:
-				t.Logf("Writed %s to inputCh\n", s)
+			case inputCh <- sampleInput[i:end]:
+				t.Logf("Writed %v to inputCh\n", sampleInput[i:end])
 			}
 		}
 	}()
@@ -116,10 +124,11 @@ func TestMultipleTransform(t *testing.T) {
 		"zzz", "aaa",
 	}
 
-	toUpperTransform := func(input []string) {
+	toUpperTransform := func(input []string) []string {
 		for i, s := range input {
 			input[i] = strings.ToUpper(s)
 		}
+		return input
 	}
 
 	validator := func(t *testing.T, bundle []string) bool {
@@ -138,3 +147,95 @@ func TestMultipleTransform(t *testing.T) {
 
 	runBundler(t, 4, sampleInput, validator, SortTransform, toUpperTransform)
 }
+
+func TestFilterTransform(t *testing.T) {
+	var result []string
+	validator := func(t *testing.T, bundle []string) bool {
+		if len(bundle) == 0 {
+			t.Error("empty bundle is sent")
+			return false
+		}
+		result = append(result, bundle...)
+		return true
+	}
+	// Whole first and last bundles are dropped
+	sampleInput := []string{"", "", "", "", "one", "", "two", "three", "", "four", "five", "", "", "", ""}
+	runBundler(t, 4, sampleInput, validator, DropEmptyTransform, SortTransform)
+
+	expected := []string{"one", "three", "two", "five", "four"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("bundles content is %v, but should be %v", result, expected)
+	}
+}
+
+func TestBundlerContent(t *testing.T) {
+	var result []string
+	validator := func(t *testing.T, bundle []string) bool {
+		result = append(result, bundle...)
+		return true
+	}
+	sampleInput := []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+	runBundler(t, 4, sampleInput, validator)
+
+	if !reflect.DeepEqual(result, sampleInput) {
+		t.Errorf("bundles content is %v, but should be %v", result, sampleInput)
+	}
+}
+
+func benchmarkBundler(b *testing.B, batchSize int) {
+	k := 100000
+	lines := make([]string, k)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inputCh := make(chan []string)
+		outputCh := GetNewBundler(k).GetBundlerCh(context.Background(), inputCh)
+		go func() {
+			defer close(inputCh)
+			for j := 0; j < len(lines); j += batchSize {
+				inputCh <- lines[j : j+batchSize]
+			}
+		}()
+		for range outputCh {
+		}
+	}
+}
+
+// BenchmarkBundler_SingleLine moves one line per channel operation
+func BenchmarkBundler_SingleLine(b *testing.B) {
+	benchmarkBundler(b, 1)
+}
+
+// BenchmarkBundler_Batch moves 1000 lines per channel operation
+func BenchmarkBundler_Batch(b *testing.B) {
+	benchmarkBundler(b, 1000)
+}
+
+func TestBundlerSpill(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	inputCh := make(chan []string)
+	go func() {
+		defer close(inputCh)
+		for i := 0; i < 20; i++ {
+			inputCh <- []string{"b", "a"}
+		}
+	}()
+
+	// Bundles are spilled after a batch under pressure once they have k/8 lines
+	b := GetNewBundler(100)
+	b.SetSpillFunc(func() bool { return true })
+	var sizes []int
+	for bundle := range b.GetBundlerCh(ctx, inputCh) {
+		sizes = append(sizes, len(bundle))
+	}
+
+	expected := []int{12, 12, 12, 4}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("bundles should be spilled at 12 lines, got sizes %v", sizes)
+	}
+}
@@ -0,0 +1,33 @@
+package bundler
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeTransform creates transform which converts lines to Unicode normalization form,
+// so canonically (NFC) or compatibly (NFKC) equivalent lines have same bytes
+func NormalizeTransform(form norm.Form) TransformFunc {
+	return func(input []string) []string {
+		for i, s := range input {
+			// Most lines are already normalized, checking is cheaper than converting
+			if !form.IsNormalString(s) {
+				input[i] = form.String(s)
+			}
+		}
+		return input
+	}
+}
+
+// GetNormalizationForm returns Unicode normalization form by name, nfc or nfkc
+func GetNormalizationForm(name string) (norm.Form, error) {
+	switch strings.ToLower(name) {
+	case "nfc":
+		return norm.NFC, nil
+	case "nfkc":
+		return norm.NFKC, nil
+	}
+	return 0, fmt.Errorf("unknown normalization form %s", name)
+}
@@ -0,0 +1,37 @@
+package bundler
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestNormalizeTransform(t *testing.T) {
+	input := []string{"München", "München", "beer", "ﬁsh"}
+
+	NormalizeTransform(norm.NFC)(input)
+	expected := []string{"München", "München", "beer", "ﬁsh"}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("NFC transform problem\nResult: %q\nExpected: %q\n", input, expected)
+	}
+
+	// Compatibility form replaces ligature too
+	NormalizeTransform(norm.NFKC)(input)
+	expected[3] = "fish"
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("NFKC transform problem\nResult: %q\nExpected: %q\n", input, expected)
+	}
+}
+
+func TestGetNormalizationForm(t *testing.T) {
+	form, err := GetNormalizationForm("NFKC")
+	if err != nil || form != norm.NFKC {
+		t.Errorf("NFKC form is %v, error %v", form, err)
+	}
+
+	_, err = GetNormalizationForm("nfx")
+	if err == nil {
+		t.Error("unknown normalization form should return error")
+	}
+}
@@ -0,0 +1,82 @@
+package bundler
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// span locates a line inside slab of RunBuffer
+type span struct {
+	start, end int
+}
+
+// RunBuffer keeps lines of a run in a single byte slab and an index of line spans
+// It is sorted by sorting the index, so lines are never moved or allocated separately
+type RunBuffer struct {
+	slab  []byte
+	index []span
+}
+
+// NewRunBuffer creates new RunBuffer entity with capacity of k lines
+func NewRunBuffer(k int) *RunBuffer {
+	return &RunBuffer{
+		index: make([]span, 0, k),
+	}
+}
+
+// Append copies s into slab as the next line
+func (rb *RunBuffer) Append(s string) {
+	start := len(rb.slab)
+	rb.slab = append(rb.slab, s...)
+	rb.index = append(rb.index, span{start, len(rb.slab)})
+}
+
+// Len number of lines in RunBuffer
+func (rb *RunBuffer) Len() int { return len(rb.index) }
+
+// Less compare two lines byte-wise
+func (rb *RunBuffer) Less(i, j int) bool {
+	return bytes.Compare(rb.Line(i), rb.Line(j)) < 0
+}
+
+// Swap swap two lines in index
+func (rb *RunBuffer) Swap(i, j int) {
+	rb.index[i], rb.index[j] = rb.index[j], rb.index[i]
+}
+
+// Line returns i-th line, returned slice refers to slab and is valid till Reset
+func (rb *RunBuffer) Line(i int) []byte {
+	s := rb.index[i]
+	return rb.slab[s.start:s.end:s.end]
+}
+
+// Sort sorts lines byte-wise
+func (rb *RunBuffer) Sort() {
+	sort.Sort(rb)
+}
+
+// WriteTo writes lines in index order to w, one line per each
+func (rb *RunBuffer) WriteTo(w io.Writer) (n int64, err error) {
+	newLine := []byte{'\n'}
+	var written int
+	for i := range rb.index {
+		written, err = w.Write(rb.Line(i))
+		n += int64(written)
+		if err != nil {
+			return
+		}
+		written, err = w.Write(newLine)
+		n += int64(written)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Reset empties RunBuffer and keeps its allocated memory to be reused
+func (rb *RunBuffer) Reset() {
+	rb.slab = rb.slab[:0]
+	rb.index = rb.index[:0]
+}
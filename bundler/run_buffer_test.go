@@ -0,0 +1,44 @@
+package bundler
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRunBuffer(t *testing.T) {
+	rb := NewRunBuffer(4)
+	for _, s := range []string{"ddd", "aaaa", "", "cccc", "bbbb"} {
+		rb.Append(s)
+	}
+
+	if rb.Len() != 5 {
+		t.Errorf("length of run buffer is %d, but should be 5", rb.Len())
+	}
+
+	rb.Sort()
+
+	var buf bytes.Buffer
+	n, err := rb.WriteTo(&buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "\naaaa\nbbbb\ncccc\nddd\n"
+	if buf.String() != expected {
+		t.Errorf("run buffer content is %q, but should be %q", buf.String(), expected)
+	}
+	if n != int64(len(expected)) {
+		t.Errorf("WriteTo returned %d, but should be %d", n, len(expected))
+	}
+
+	rb.Reset()
+	if rb.Len() != 0 {
+		t.Errorf("length of run buffer after reset is %d, but should be 0", rb.Len())
+	}
+
+	rb.Append("zzz")
+	if string(rb.Line(0)) != "zzz" {
+		t.Errorf("first line after reset is %q, but should be \"zzz\"", rb.Line(0))
+	}
+}
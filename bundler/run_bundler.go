@@ -0,0 +1,95 @@
+package bundler
+
+import (
+	"context"
+)
+
+// runBuffers is the number of RunBuffers are reused by RunBundler,
+// one is filled while the other one is stored
+const runBuffers = 2
+
+// RunBundler creates sorted runs of size k in RunBuffers
+// RunBuffers are reused, so each one should be released after it is stored
+type RunBundler struct {
+	k     int
+	free  chan *RunBuffer // RunBuffers ready to be filled
+	spill SpillFunc       // checked after each batch once run has k/minSpillDivisor lines, nil means runs are always full
+}
+
+// GetNewRunBundler creates new RunBundler entity which creates sorted runs of size k
+func GetNewRunBundler(k int) *RunBundler {
+	rb := &RunBundler{
+		k:    k,
+		free: make(chan *RunBuffer, runBuffers),
+	}
+	for i := 0; i < runBuffers; i++ {
+		rb.free <- NewRunBuffer(k)
+	}
+	return rb
+}
+
+// SetSpillFunc sets f to check whether current run should be sent before it is full
+func (b *RunBundler) SetSpillFunc(f SpillFunc) {
+	b.spill = f
+}
+
+// Release gives back RunBuffer received from run channel to be filled again
+func (b *RunBundler) Release(buffer *RunBuffer) {
+	buffer.Reset()
+	b.free <- buffer
+}
+
+// GetRunCh returns channel of sorted RunBuffers filled by lines of inCh
+func (b *RunBundler) GetRunCh(ctx context.Context, inCh <-chan []string) <-chan *RunBuffer {
+	ch := make(chan *RunBuffer)
+
+	go func() {
+		defer close(ch)
+
+		var buffer *RunBuffer
+		select {
+		case <-ctx.Done():
+			return
+		case buffer = <-b.free:
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case batch, ok := <-inCh:
+				if !ok {
+					buffer.Sort()
+					select {
+					case <-ctx.Done():
+					case ch <- buffer:
+					}
+					return
+				}
+
+				for i, s := range batch {
+					buffer.Append(s)
+					if buffer.Len() < b.k && (i < len(batch)-1 || !shouldSpill(b.spill, buffer.Len(), b.k)) {
+						continue
+					}
+
+					buffer.Sort()
+					select {
+					case <-ctx.Done():
+						return
+					case ch <- buffer:
+					}
+
+					// Wait till a stored buffer is released
+					select {
+					case <-ctx.Done():
+						return
+					case buffer = <-b.free:
+					}
+				}
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,148 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBundler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	sampleInput := []string{
+		"zzz", "hhh", "ddd", "aaa",
+		"aaa", "ddd", "bbb", "aba",
+		"aaa", "bbb", "ccc", "ddd",
+		"zzz", "aaa",
+	}
+	k := 4
+
+	inputCh := make(chan []string)
+	b := GetNewRunBundler(k)
+	runCh := b.GetRunCh(ctx, inputCh)
+
+	go func() {
+		defer close(inputCh)
+		for i := 0; i < len(sampleInput); i += 3 {
+			end := i + 3
+			if end > len(sampleInput) {
+				end = len(sampleInput)
+			}
+			inputCh <- sampleInput[i:end]
+		}
+	}()
+
+	var result []string
+	buffers := make(map[*RunBuffer]bool)
+	for run := range runCh {
+		buffers[run] = true
+
+		if run.Len() > k {
+			t.Errorf("Invalid run size %d (expected %d)", run.Len(), k)
+		}
+
+		var buf bytes.Buffer
+		_, err := run.WriteTo(&buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if !sort.StringsAreSorted(lines) {
+			t.Errorf("run is not sorted: %v", lines)
+		}
+		result = append(result, lines...)
+
+		b.Release(run)
+	}
+
+	if len(buffers) > runBuffers {
+		t.Errorf("%d run buffers are used, but should be reused up to %d", len(buffers), runBuffers)
+	}
+
+	sort.Strings(result)
+	sort.Strings(sampleInput)
+	if strings.Join(result, ",") != strings.Join(sampleInput, ",") {
+		t.Errorf("runs content is %v, but should be %v", result, sampleInput)
+	}
+}
+
+func benchmarkInput(k int) []string {
+	lines := make([]string, k)
+	for i := range lines {
+		lines[i] = strconv.Itoa((i * 7919) % k)
+	}
+	return lines
+}
+
+// BenchmarkRunBundler creates sorted runs in reused RunBuffers
+func BenchmarkRunBundler(b *testing.B) {
+	k := 100000
+	lines := benchmarkInput(k)
+
+	bundler := GetNewRunBundler(k)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inputCh := make(chan []string, 1)
+		inputCh <- lines
+		close(inputCh)
+		for run := range bundler.GetRunCh(context.Background(), inputCh) {
+			bundler.Release(run)
+		}
+	}
+}
+
+// BenchmarkBundler_SortTransform creates sorted bundles of strings
+func BenchmarkBundler_SortTransform(b *testing.B) {
+	k := 100000
+	lines := benchmarkInput(k)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Bundle is sorted in place, so input is copied
+		batch := make([]string, k)
+		copy(batch, lines)
+		inputCh := make(chan []string, 1)
+		inputCh <- batch
+		close(inputCh)
+		bundler := GetNewBundler(k)
+		bundler.AddTransformFunc(SortTransform)
+		for range bundler.GetBundlerCh(context.Background(), inputCh) {
+		}
+	}
+}
+
+func TestRunBundlerSpill(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	inputCh := make(chan []string)
+	go func() {
+		defer close(inputCh)
+		for i := 0; i < 19; i++ {
+			inputCh <- []string{"b", "a", "c"}
+		}
+	}()
+
+	// Runs are spilled after a batch under pressure once they have k/8 lines
+	b := GetNewRunBundler(100)
+	b.SetSpillFunc(func() bool { return true })
+	var sizes []int
+	for run := range b.GetRunCh(ctx, inputCh) {
+		sizes = append(sizes, run.Len())
+		b.Release(run)
+	}
+
+	expected := []int{12, 12, 12, 12, 9}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("runs should be spilled at 12 lines, got sizes %v", sizes)
+	}
+}
@@ -0,0 +1,229 @@
+package bundler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// TrimTransform removes leading and trailing white space of lines
+func TrimTransform(input []string) []string {
+	for i, s := range input {
+		input[i] = strings.TrimSpace(s)
+	}
+	return input
+}
+
+// LowercaseTransform converts lines to lower case
+func LowercaseTransform(input []string) []string {
+	for i, s := range input {
+		input[i] = strings.ToLower(s)
+	}
+	return input
+}
+
+// CollapseSpacesTransform replaces each run of white space in lines by a single space
+func CollapseSpacesTransform(input []string) []string {
+	for i, s := range input {
+		input[i] = collapseSpaces(s)
+	}
+	return input
+}
+
+// collapseSpaces replaces each run of white space in s by a single space
+func collapseSpaces(s string) string {
+	if !hasSpaceRun(s) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// hasSpaceRun checks whether s has white space other than single spaces
+func hasSpaceRun(s string) bool {
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) && (space || r != ' ') {
+			return true
+		}
+		space = unicode.IsSpace(r)
+	}
+	return false
+}
+
+// StripPunctuationTransform removes Unicode punctuation characters from lines
+func StripPunctuationTransform(input []string) []string {
+	for i, s := range input {
+		if strings.IndexFunc(s, unicode.IsPunct) != -1 {
+			input[i] = strings.Map(func(r rune) rune {
+				if unicode.IsPunct(r) {
+					return -1
+				}
+				return r
+			}, s)
+		}
+	}
+	return input
+}
+
+// DropTransform creates transform which drops lines for which drop returns true,
+// remaining lines are kept in order in the input slice
+func DropTransform(drop func(s string) bool) TransformFunc {
+	return func(input []string) []string {
+		output := input[:0]
+		for _, s := range input {
+			if !drop(s) {
+				output = append(output, s)
+			}
+		}
+		// Release dropped strings
+		for i := len(output); i < len(input); i++ {
+			input[i] = ""
+		}
+		return output
+	}
+}
+
+// DropEmptyTransform drops empty lines
+var DropEmptyTransform = DropTransform(func(s string) bool { return s == "" })
+
+// DropMatchingTransform creates transform which drops lines matching re
+func DropMatchingTransform(re *regexp.Regexp) TransformFunc {
+	return DropTransform(re.MatchString)
+}
+
+// DropStopwordsTransform creates transform which drops lines equal to any of stopwords
+func DropStopwordsTransform(stopwords []string) TransformFunc {
+	set := make(map[string]struct{}, len(stopwords))
+	for _, w := range stopwords {
+		set[w] = struct{}{}
+	}
+	return DropTransform(func(s string) bool {
+		_, ok := set[s]
+		return ok
+	})
+}
+
+// readStopwords reads stopwords of file located at path, one per line, empty lines are ignored
+func readStopwords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var stopwords []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if w := strings.TrimSpace(scanner.Text()); w != "" {
+			stopwords = append(stopwords, w)
+		}
+	}
+	return stopwords, scanner.Err()
+}
+
+// GetTransform returns transform by name, argument of parametric transforms follows
+// the name after a colon:
+// trim, lower, collapse, strip-punct, drop-empty, nfc, nfkc,
+// drop-regex:<pattern> and stopwords:<path of file with one stopword per line>
+func GetTransform(spec string) (TransformFunc, error) {
+	name, arg := spec, ""
+	if i := strings.IndexByte(spec, ':'); i != -1 {
+		name, arg = spec[:i], spec[i+1:]
+	}
+
+	switch name {
+	case "trim":
+		return TrimTransform, nil
+	case "lower":
+		return LowercaseTransform, nil
+	case "collapse":
+		return CollapseSpacesTransform, nil
+	case "strip-punct":
+		return StripPunctuationTransform, nil
+	case "drop-empty":
+		return DropEmptyTransform, nil
+	case "nfc":
+		return NormalizeTransform(norm.NFC), nil
+	case "nfkc":
+		return NormalizeTransform(norm.NFKC), nil
+	case "drop-regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of drop-regex: %v", err)
+		}
+		return DropMatchingTransform(re), nil
+	case "stopwords":
+		stopwords, err := readStopwords(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading stopwords: %v", err)
+		}
+		return DropStopwordsTransform(stopwords), nil
+	}
+	return nil, fmt.Errorf("unknown transform %s", name)
+}
+
+// transformNames are names of transforms of GetTransform, parametric ones end with the colon before their argument
+var transformNames = []string{"trim", "lower", "collapse", "strip-punct", "drop-empty", "nfc", "nfkc", "drop-regex:", "stopwords:"}
+
+// startsTransform checks whether s is a spec of transform by its name
+func startsTransform(s string) bool {
+	for _, name := range transformNames {
+		if s == name || (strings.HasSuffix(name, ":") && strings.HasPrefix(s, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitTransforms splits comma separated list of transform specs,
+// a comma separates specs only if a transform name follows it, so arguments may have commas, e.g. drop-regex:a{1,3}
+func SplitTransforms(list string) []string {
+	if list == "" {
+		return nil
+	}
+	parts := strings.Split(list, ",")
+	specs := parts[:1]
+	for _, part := range parts[1:] {
+		if startsTransform(part) {
+			specs = append(specs, part)
+		} else {
+			specs[len(specs)-1] += "," + part
+		}
+	}
+	return specs
+}
+
+// GetTransforms returns transforms of specs in order
+func GetTransforms(specs []string) ([]TransformFunc, error) {
+	transforms := make([]TransformFunc, 0, len(specs))
+	for _, spec := range specs {
+		t, err := GetTransform(spec)
+		if err != nil {
+			return nil, err
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
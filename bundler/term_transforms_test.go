@@ -0,0 +1,87 @@
+package bundler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTermTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform TransformFunc
+		input     []string
+		expected  []string
+	}{
+		{"trim", TrimTransform, []string{" beer  ", "\tbeer", "beer"}, []string{"beer", "beer", "beer"}},
+		{"lower", LowercaseTransform, []string{"Beer", "MÜNCHEN"}, []string{"beer", "münchen"}},
+		{"collapse", CollapseSpacesTransform, []string{"king  ludwig", " a\t\tb ", "a b"}, []string{"king ludwig", " a b ", "a b"}},
+		{"strip-punct", StripPunctuationTransform, []string{"beer!", "\"king\" ludwig's", "a-b"}, []string{"beer", "king ludwigs", "ab"}},
+		{"drop-empty", DropEmptyTransform, []string{"", "beer", "", " "}, []string{"beer", " "}},
+		{"drop-regex", DropMatchingTransform(regexp.MustCompile(`^https?://`)), []string{"http://a.de", "beer", "https://b.de"}, []string{"beer"}},
+		{"stopwords", DropStopwordsTransform([]string{"the", "a"}), []string{"the", "beer", "a", "the beer"}, []string{"beer", "the beer"}},
+	}
+
+	for _, test := range tests {
+		result := test.transform(test.input)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s transform problem\nResult: %q\nExpected: %q\n", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestGetTransforms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transforms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	stopwordsPath := filepath.Join(dir, "stopwords.txt")
+	err = ioutil.WriteFile(stopwordsPath, []byte("the\n\n  and \n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Transforms are applied in order, lines are lower cased before stopwords are dropped
+	transforms, err := GetTransforms([]string{"trim", "lower", "strip-punct", "collapse", "drop-empty", "stopwords:" + stopwordsPath, "drop-regex:^[0-9]+$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []string{" The ", "King  Ludwig!", "...", "AND", "1234", "beer 2"}
+	for _, transform := range transforms {
+		input = transform(input)
+	}
+	expected := []string{"king ludwig", "beer 2"}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("transforms problem\nResult: %q\nExpected: %q\n", input, expected)
+	}
+
+	for _, spec := range []string{"upper", "drop-regex:(", "stopwords:" + filepath.Join(dir, "missing")} {
+		_, err = GetTransform(spec)
+		if err == nil {
+			t.Errorf("transform %s should return error", spec)
+		}
+	}
+}
+
+func TestSplitTransforms(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected []string
+	}{
+		{"", nil},
+		{"trim,lower", []string{"trim", "lower"}},
+		{"drop-regex:a{1,3},lower", []string{"drop-regex:a{1,3}", "lower"}},
+		{"drop-regex:^(a,b)$,stopwords:/tmp/a,b", []string{"drop-regex:^(a,b)$", "stopwords:/tmp/a,b"}},
+	}
+	for _, test := range tests {
+		if specs := SplitTransforms(test.list); !reflect.DeepEqual(specs, test.expected) {
+			t.Errorf("specs of %q are %q, but should be %q", test.list, specs, test.expected)
+		}
+	}
+}
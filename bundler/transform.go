@@ -1,8 +1,148 @@
 package bundler
 
-import "sort"
+import (
+	"AID/solution/comparator"
+	"sort"
+)
 
 // SortTransform sort bundle by quick sort algorithm implemented by sort package
-func SortTransform(input []string) {
+func SortTransform(input []string) []string {
 	sort.Strings(input)
+	return input
+}
+
+// keyedStrings sorts strings by their precomputed keys
+type keyedStrings struct {
+	keys, values []string
+}
+
+func (ks keyedStrings) Len() int           { return len(ks.values) }
+func (ks keyedStrings) Less(i, j int) bool { return ks.keys[i] < ks.keys[j] }
+func (ks keyedStrings) Swap(i, j int) {
+	ks.keys[i], ks.keys[j] = ks.keys[j], ks.keys[i]
+	ks.values[i], ks.values[j] = ks.values[j], ks.values[i]
+}
+
+// stableKeyedStrings sorts strings by their precomputed keys and strings with equal keys by their origins
+type stableKeyedStrings struct {
+	keyedStrings
+	cmp *comparator.Comparator
+}
+
+func (ks stableKeyedStrings) Less(i, j int) bool {
+	if ks.keys[i] != ks.keys[j] {
+		return ks.keys[i] < ks.keys[j]
+	}
+	return ks.cmp.LessOrigin(ks.values[i], ks.values[j])
+}
+
+// KeySortTransform creates transform which sorts bundle by keys of cmp,
+// keys are computed once per line
+func KeySortTransform(cmp *comparator.Comparator) TransformFunc {
+	if cmp.IsBytewise() {
+		return SortTransform
+	}
+
+	return func(input []string) []string {
+		keys := make([]string, len(input))
+		for i, s := range input {
+			keys[i] = cmp.Key(s)
+		}
+		if cmp.IsStable() {
+			sort.Sort(stableKeyedStrings{keyedStrings{keys, input}, cmp})
+			return input
+		}
+		sort.Sort(keyedStrings{keys, input})
+		return input
+	}
+}
+
+// radixCutoff is the size of buckets which are sorted by multikey quicksort rather than radix sort
+const radixCutoff = 64
+
+// insertionCutoff is the size of buckets which are sorted by insertion sort rather than multikey quicksort
+const insertionCutoff = 8
+
+// RadixSortTransform sort bundle byte-wise by MSD radix sort, small buckets are sorted by multikey quicksort
+// Result is same as SortTransform
+func RadixSortTransform(input []string) []string {
+	if len(input) < 2 {
+		return input
+	}
+	aux := make([]string, len(input))
+	msdRadixSort(input, aux, 0)
+	return input
+}
+
+// charAt returns byte at position d of s, or -1 if s is shorter
+func charAt(s string, d int) int {
+	if d < len(s) {
+		return int(s[d])
+	}
+	return -1
+}
+
+// msdRadixSort sorts a whose strings share first d bytes, aux is scratch space of same length
+func msdRadixSort(a, aux []string, d int) {
+	if len(a) <= radixCutoff {
+		multikeyQuicksort(a, d)
+		return
+	}
+
+	// count[c+2] is frequency of byte c at position d, c is -1 for strings ending before d
+	var count [256 + 2]int
+	for _, s := range a {
+		count[charAt(s, d)+2]++
+	}
+	for r := 0; r < 256+1; r++ {
+		count[r+1] += count[r]
+	}
+	for _, s := range a {
+		c := charAt(s, d) + 1
+		aux[count[c]] = s
+		count[c]++
+	}
+	copy(a, aux[:len(a)])
+
+	// Now bucket of byte r is a[count[r]:count[r+1]], strings ended before d are already in place
+	for r := 0; r < 256; r++ {
+		if count[r+1]-count[r] > 1 {
+			msdRadixSort(a[count[r]:count[r+1]], aux[count[r]:count[r+1]], d+1)
+		}
+	}
+}
+
+// multikeyQuicksort sorts a whose strings share first d bytes by 3-way partitioning on byte d
+func multikeyQuicksort(a []string, d int) {
+	for len(a) > insertionCutoff {
+		lt, gt := 0, len(a)-1
+		v := charAt(a[len(a)/2], d)
+		for i := 0; i <= gt; {
+			c := charAt(a[i], d)
+			switch {
+			case c < v:
+				a[lt], a[i] = a[i], a[lt]
+				lt++
+				i++
+			case c > v:
+				a[i], a[gt] = a[gt], a[i]
+				gt--
+			default:
+				i++
+			}
+		}
+
+		multikeyQuicksort(a[:lt], d)
+		if v >= 0 {
+			multikeyQuicksort(a[lt:gt+1], d+1)
+		}
+		a = a[gt+1:]
+	}
+
+	// Insertion sort, only bytes after d are compared
+	for i := 1; i < len(a); i++ {
+		for j := i; j > 0 && a[j][d:] < a[j-1][d:]; j-- {
+			a[j], a[j-1] = a[j-1], a[j]
+		}
+	}
 }
@@ -1,7 +1,10 @@
 package bundler
 
 import (
+	"AID/solution/comparator"
+	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -26,3 +29,73 @@ func TestTransformSort1(t *testing.T) {
 		t.Errorf("Sort transform problem\nResult: %v\nExpected: %v\n", input, expected)
 	}
 }
+
+// randomTerms generates search term like strings which share prefixes
+func randomTerms(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	prefixes := []string{"", "beer", "bratwurst", "king ludwig", "münchen ", "k"}
+	terms := make([]string, n)
+	for i := range terms {
+		suffix := make([]byte, r.Intn(12))
+		for j := range suffix {
+			suffix[j] = byte('a' + r.Intn(26))
+		}
+		terms[i] = prefixes[r.Intn(len(prefixes))] + string(suffix)
+	}
+	return terms
+}
+
+func TestKeySortTransform(t *testing.T) {
+	input := []string{"beer", "Apple", "BEER", "apple", "Currywurst", "bratwurst"}
+
+	KeySortTransform(comparator.CaseFold)(input)
+
+	for i := 1; i < len(input); i++ {
+		if comparator.CaseFold.Less(input[i], input[i-1]) {
+			t.Errorf("bundle is not sorted ignoring case: %v", input)
+			break
+		}
+	}
+}
+
+func TestRadixSortTransform(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 100, 10000} {
+		input := randomTerms(n)
+		expected := make([]string, n)
+		copy(expected, input)
+		sort.Strings(expected)
+
+		RadixSortTransform(input)
+
+		if !reflect.DeepEqual(input, expected) {
+			t.Errorf("radix sort result of %d terms is not same as sort.Strings", n)
+		}
+	}
+
+	input := []string{"b", "", "\xff", "a\x00", "a", "ab", "\x00"}
+	RadixSortTransform(input)
+	if !sort.StringsAreSorted(input) {
+		t.Errorf("bundle is not sorted: %q", input)
+	}
+}
+
+func benchmarkSort(b *testing.B, transform TransformFunc) {
+	terms := randomTerms(1000000)
+	input := make([]string, len(terms))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		copy(input, terms)
+		b.StartTimer()
+		transform(input)
+	}
+}
+
+func BenchmarkSortTransform(b *testing.B) {
+	benchmarkSort(b, SortTransform)
+}
+
+func BenchmarkRadixSortTransform(b *testing.B) {
+	benchmarkSort(b, RadixSortTransform)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"AID/solution/distributed"
+	"AID/solution/sorter"
+	"flag"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runWorker serves sort requests of a coordinator, input files should be accessible by the worker under its root
+func runWorker(args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:9090", "address to listen for sort requests, requests are not authenticated")
+	root := flags.String("root", "", "directory of input files, files out of it are not read")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// Settings of this worker are checked, requests may have other ones
+	cfg, err := newSortConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Count {
+		return fmt.Errorf("count aggregation is not supported by worker command")
+	}
+
+	tempPaths, err := getTempPaths()
+	if err != nil {
+		return err
+	}
+
+	var pressure func() bool
+	if monitor := startMemoryMonitor(); monitor != nil {
+		defer monitor.Stop()
+		pressure = monitor.UnderPressure
+	}
+
+	// Lines are sorted by settings of coordinator, the rest of config is of this worker
+	worker, err := distributed.NewWorker(*root, tempPaths, func(settings distributed.Settings) (sorter.Config, error) {
+		cfg, err := newSortConfigFor(settings)
+		cfg.Pressure = pressure
+		return cfg, err
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Worker listens on %s and reads files under %s", *listen, *root)
+	return http.ListenAndServe(*listen, worker.Handler())
+}
+
+// runCoordinator distributes input directory files across workers and merges their results into output path
+func runCoordinator(args []string) error {
+	flags := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	workers := flags.String("workers", "", "comma separated base URLs of workers, e.g. http://host:9090")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	workerURLs := splitList(*workers)
+	if len(workerURLs) == 0 {
+		return fmt.Errorf("at least one worker is needed")
+	}
+
+	cfg, err := newSortConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Count {
+		return fmt.Errorf("count aggregation is not supported by coordinator command")
+	}
+
+	dirSerializer, err := newDirSerializer()
+	if err != nil {
+		return err
+	}
+	files, err := dirSerializer.Files()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newSignalContext()
+	defer cancel()
+
+	err = distributed.NewCoordinator(workerURLs, flagSettings(), cfg.Comparator).Sort(ctx, files, *outputPath)
+	if err != nil {
+		return fmt.Errorf("error in distributed sort: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"AID/solution/sorter"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runMerge merges already sorted files given as arguments into output path
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	trustSorted := flags.Bool("trust-sorted", false, "don't check whether input files are sorted")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] merge [-trust-sorted] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		return fmt.Errorf("no file is given to merge")
+	}
+
+	cfg, err := newSortConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Count {
+		return fmt.Errorf("count aggregation is not supported by merge command")
+	}
+
+	tempPaths, err := getTempPaths()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newSignalContext()
+	defer cancel()
+
+	err = sorter.MergeFiles(ctx, files, tempPaths, *outputPath, cfg, *trustSorted)
+	if err != nil {
+		return fmt.Errorf("error in merge: %v", err)
+	}
+	cfg.Blank.Report()
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"AID/solution/inputserializer"
+	"AID/solution/setops"
+	"AID/solution/sorter"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runSetOperation writes result of set operation on files given as arguments into output path
+// Files which are not sorted are sorted first
+func runSetOperation(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	var suppress1, suppress2, suppress3 *bool
+	if command == "comm" {
+		suppress1 = flags.Bool("1", false, "suppress lines only in the first file")
+		suppress2 = flags.Bool("2", false, "suppress lines only in the second file")
+		suppress3 = flags.Bool("3", false, "suppress lines in both files")
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] %s [flags] file file...\n", os.Args[0], command)
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var op setops.Operation
+	files := flags.Args()
+	switch command {
+	case "union":
+		op = setops.Union
+	case "intersect":
+		op = setops.Intersect
+	case "diff":
+		op = setops.Diff
+	case "comm":
+		if len(files) != 2 {
+			return fmt.Errorf("comm needs exactly two files")
+		}
+		op = setops.Comm(*suppress1, *suppress2, *suppress3)
+	}
+	if len(files) < 2 {
+		return fmt.Errorf("%s needs at least two files", command)
+	}
+
+	cfg, err := newSortConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Count {
+		return fmt.Errorf("count aggregation is not supported by %s command", command)
+	}
+
+	tempPaths, err := getTempPaths()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newSignalContext()
+	defer cancel()
+
+	sortedPaths, clean, err := sorter.SortedFiles(ctx, files, tempPaths, cfg)
+	if err != nil {
+		return fmt.Errorf("error in sorting input files: %v", err)
+	}
+	defer clean()
+
+	chs := make([]<-chan []string, 0, len(sortedPaths))
+	for _, sortedPath := range sortedPaths {
+		ch, err := inputserializer.NewFileListSerializer([]string{sortedPath}).GetSerializerCh(ctx)
+		if err != nil {
+			return err
+		}
+		chs = append(chs, ch)
+	}
+
+	output, err := os.Create(*outputPath)
+	if err != nil {
+		return err
+	}
+	err = setops.Run(ctx, chs, output, cfg.Comparator, op)
+	closeErr := output.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error in %s: %v", command, err)
+	}
+
+	return nil
+}
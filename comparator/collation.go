@@ -0,0 +1,61 @@
+package comparator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// collationPrefix is prefix of names of collation comparators, e.g. collate:de
+const collationPrefix = "collate"
+
+// Collation creates comparator which orders lines by Unicode collation of locale,
+// language.Und is the root collation
+// Keys are collation keys, so they are computed once per line and compared byte-wise
+func Collation(locale language.Tag) *Comparator {
+	// Collator is not safe for concurrent use, so each goroutine takes one from pool
+	pool := sync.Pool{
+		New: func() interface{} {
+			return &collationBuffer{collator: collate.New(locale)}
+		},
+	}
+
+	name := collationPrefix
+	if locale != language.Und {
+		name += ":" + locale.String()
+	}
+
+	return &Comparator{
+		Name: name,
+		key: func(s string) string {
+			cb := pool.Get().(*collationBuffer)
+			cb.buffer.Reset()
+			key := string(cb.collator.KeyFromString(&cb.buffer, s))
+			pool.Put(cb)
+			return key
+		},
+	}
+}
+
+// collationBuffer is collator with its key buffer
+type collationBuffer struct {
+	collator *collate.Collator
+	buffer   collate.Buffer
+}
+
+// getCollation returns collation comparator by name, collate or collate:<locale>
+func getCollation(name string) (*Comparator, error) {
+	if name == collationPrefix {
+		return Collation(language.Und), nil
+	}
+
+	locale := strings.TrimPrefix(name, collationPrefix+":")
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale of collation %s: %v", locale, err)
+	}
+	return Collation(tag), nil
+}
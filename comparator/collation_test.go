@@ -0,0 +1,63 @@
+package comparator
+
+import (
+	"sort"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestCollation(t *testing.T) {
+	c, err := Get("collate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.IsBytewise() {
+		t.Error("collation should not be bytewise")
+	}
+
+	if !c.Less("Äpfel", "zebra") {
+		t.Error("Äpfel should be less than zebra by collation")
+	}
+	if !c.Less("apple", "Beer") {
+		t.Error("apple should be less than Beer by collation")
+	}
+
+	// NFC and NFD forms of same term have same key
+	if c.Key("München") != c.Key("München") {
+		t.Error("NFC and NFD forms of München should have same key")
+	}
+
+	lines := []string{"zebra", "Zürich", "Äpfel", "apfel", "Bier"}
+	sort.Slice(lines, func(i, j int) bool { return c.Less(lines[i], lines[j]) })
+	expected := []string{"apfel", "Äpfel", "Bier", "zebra", "Zürich"}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("collation order is %q, expected %q", lines, expected)
+			break
+		}
+	}
+}
+
+func TestCollation_Locale(t *testing.T) {
+	c, err := Get("collate:sv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "collate:sv" {
+		t.Errorf("name of collation is %s", c.Name)
+	}
+
+	// Swedish orders ä after z, unlike root collation
+	if !c.Less("zebra", "äpple") {
+		t.Error("zebra should be less than äpple by Swedish collation")
+	}
+	if !Collation(language.Und).Less("äpple", "zebra") {
+		t.Error("äpple should be less than zebra by root collation")
+	}
+
+	_, err = Get("collate:not a locale")
+	if err == nil {
+		t.Error("invalid locale should return error")
+	}
+}
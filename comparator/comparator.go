@@ -0,0 +1,155 @@
+package comparator
+
+import (
+	"AID/solution/record"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+)
+
+// Comparator orders lines by their normalized keys,
+// lines are ordered byte-wise by keys and lines with equal keys are equal
+type Comparator struct {
+	Name   string
+	key    func(s string) string // nil means line itself is the key
+	stable bool                  // lines are tagged by origins, lines with equal keys are ordered by origins
+}
+
+// Bytewise orders lines byte-wise
+var Bytewise = &Comparator{Name: "bytewise"}
+
+// CaseFold orders lines byte-wise by their Unicode case folding, e.g. "STRASSE" and "straße" are equal
+var CaseFold = &Comparator{Name: "fold", key: foldCase}
+
+// foldCasers keeps case folding casers, a Caser is not safe for concurrent use
+var foldCasers = sync.Pool{
+	New: func() interface{} {
+		caser := cases.Fold()
+		return &caser
+	},
+}
+
+// foldCase returns Unicode full case folding of s
+func foldCase(s string) string {
+	if isASCII(s) {
+		// Folding of ASCII letters is their lower case
+		return strings.ToLower(s)
+	}
+	caser := foldCasers.Get().(*cases.Caser)
+	folded := caser.String(s)
+	foldCasers.Put(caser)
+	return folded
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// comparators by name
+var comparators = map[string]*Comparator{
+	Bytewise.Name: Bytewise,
+	CaseFold.Name: CaseFold,
+}
+
+// New creates comparator ordering lines by keys generated by key function
+func New(name string, key func(s string) string) *Comparator {
+	return &Comparator{Name: name, key: key}
+}
+
+// Stable creates comparator of lines tagged by origins (see record package) which orders them by cmp,
+// lines with equal keys are ordered by their origins
+func Stable(cmp *Comparator) *Comparator {
+	return &Comparator{
+		Name: cmp.Name + "-stable",
+		key: func(s string) string {
+			return cmp.Key(record.Line(s))
+		},
+		stable: true,
+	}
+}
+
+// Get returns comparator by name, collation comparators are named collate or collate:<locale>
+func Get(name string) (*Comparator, error) {
+	if name == collationPrefix || strings.HasPrefix(name, collationPrefix+":") {
+		return getCollation(name)
+	}
+
+	c, ok := comparators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown comparator %s", name)
+	}
+	return c, nil
+}
+
+// IsBytewise checks whether keys are lines themselves
+func (c *Comparator) IsBytewise() bool {
+	return c.key == nil
+}
+
+// IsStable checks whether lines are tagged by origins
+func (c *Comparator) IsStable() bool {
+	return c.stable
+}
+
+// LessOrigin compares origins of two lines with equal keys, it is always false if c is not stable
+func (c *Comparator) LessOrigin(a, b string) bool {
+	return c.stable && record.Origin(a) < record.Origin(b)
+}
+
+// CompareOrigin compares origins of two lines with equal keys like strings.Compare,
+// it is always 0 if c is not stable
+func (c *Comparator) CompareOrigin(a, b string) int {
+	if !c.stable {
+		return 0
+	}
+	return strings.Compare(record.Origin(a), record.Origin(b))
+}
+
+// Key returns normalized key of s
+func (c *Comparator) Key(s string) string {
+	if c.key == nil {
+		return s
+	}
+	return c.key(s)
+}
+
+// Less compares two lines by their keys
+func (c *Comparator) Less(a, b string) bool {
+	if c.key == nil {
+		return a < b
+	}
+	if !c.stable {
+		return c.key(a) < c.key(b)
+	}
+	keyA, keyB := c.key(a), c.key(b)
+	if keyA != keyB {
+		return keyA < keyB
+	}
+	return c.LessOrigin(a, b)
+}
+
+// Prefix returns first 8 bytes of key as a big-endian integer, shorter keys are padded by zero
+// Prefixes are ordered same as keys, but keys with equal prefixes should be compared fully
+func Prefix(key string) uint64 {
+	if len(key) >= 8 {
+		return binary.BigEndian.Uint64([]byte(key[:8]))
+	}
+
+	var prefix uint64
+	for i := 0; i < 8; i++ {
+		prefix <<= 8
+		if i < len(key) {
+			prefix |= uint64(key[i])
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,82 @@
+package comparator
+
+import (
+	"AID/solution/record"
+	"sort"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	keys := []string{"", "\x00", "a", "a\x00", "ab", "abcdefgh", "abcdefghi", "abcdefgi", "b", "\xff\xff\xff\xff\xff\xff\xff\xff"}
+	if !sort.StringsAreSorted(keys) {
+		t.Fatal("test keys should be sorted")
+	}
+
+	for i := 1; i < len(keys); i++ {
+		if Prefix(keys[i-1]) > Prefix(keys[i]) {
+			t.Errorf("prefix of %q is greater than prefix of %q", keys[i-1], keys[i])
+		}
+	}
+
+	if Prefix("abcdefgh") != 0x6162636465666768 {
+		t.Errorf("prefix of \"abcdefgh\" is %x", Prefix("abcdefgh"))
+	}
+	if Prefix("ab") != 0x6162000000000000 {
+		t.Errorf("prefix of \"ab\" is %x", Prefix("ab"))
+	}
+}
+
+func TestGet(t *testing.T) {
+	c, err := Get("fold")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if c != CaseFold {
+		t.Error("fold should be case folding comparator")
+	}
+
+	_, err = Get("unknown")
+	if err == nil {
+		t.Error("unknown comparator should return error")
+	}
+}
+
+func TestCaseFold(t *testing.T) {
+	if !CaseFold.Less("apple", "Beer") {
+		t.Error("apple should be less than Beer ignoring case")
+	}
+	if CaseFold.Less("Beer", "beer") || CaseFold.Less("beer", "Beer") {
+		t.Error("Beer and beer should be equal ignoring case")
+	}
+	if Bytewise.Less("apple", "Beer") {
+		t.Error("apple should not be less than Beer byte-wise")
+	}
+	// Full case folding is not lower case
+	for _, equal := range [][2]string{{"STRASSE", "straße"}, {"ΣΑΣ", "σας"}, {"ς", "σ"}} {
+		if CaseFold.Key(equal[0]) != CaseFold.Key(equal[1]) {
+			t.Errorf("%s and %s should be equal by case folding", equal[0], equal[1])
+		}
+	}
+	if CaseFold.IsBytewise() || !Bytewise.IsBytewise() {
+		t.Error("only Bytewise should be byte-wise")
+	}
+}
+
+func TestStable(t *testing.T) {
+	cmp := Stable(CaseFold)
+	if cmp.IsBytewise() || !cmp.IsStable() || CaseFold.IsStable() {
+		t.Error("only stable comparator should be stable")
+	}
+
+	first, second := record.Tag("Beer", 0, 7), record.Tag("beer", 1, 0)
+	if !cmp.Less(first, second) || cmp.Less(second, first) {
+		t.Error("equal keys should be ordered by origins")
+	}
+	if !cmp.Less(record.Tag("apple", 9, 0), first) {
+		t.Error("keys should be compared before origins")
+	}
+	if cmp.Key(first) != "beer" {
+		t.Errorf("key of %q should not have origin", first)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"AID/solution/sorter"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// envPrefix is prefix of environment variables overriding settings, e.g. SORTTERMS_IO_MEMORY for -io-memory
+const envPrefix = "SORTTERMS_"
+
+// profilesKey is key of profiles in config file
+const profilesKey = "profiles"
+
+var (
+	configPath  = flag.String("config", "", "JSON config file of settings by flag names, with optional profiles")
+	profileName = flag.String("profile", "", "profile of config file applied over its top level settings")
+	printConfig = flag.Bool("print-config", false, "validate and print effective settings as JSON, then exit")
+)
+
+// settings are flag values by flag names
+type settings map[string]string
+
+// envName returns name of environment variable of flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// isMetaFlag checks whether flag selects settings rather than being a setting
+func isMetaFlag(name string) bool {
+	return name == "config" || name == "profile" || name == "print-config"
+}
+
+// decodeSettings converts JSON object of settings to flag values
+func decodeSettings(raw map[string]json.RawMessage) (settings, error) {
+	result := make(settings, len(raw))
+	for name, value := range raw {
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var v interface{}
+		err := decoder.Decode(&v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", name, err)
+		}
+
+		switch v := v.(type) {
+		case string:
+			result[name] = v
+		case json.Number:
+			result[name] = v.String()
+		case bool:
+			result[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of %s should be string, number or boolean", name)
+		}
+	}
+	return result, nil
+}
+
+// loadConfigFile reads settings of config file located at path, settings of profile override top level ones
+// Config file is a JSON object of settings by flag names and "profiles" object of settings by profile names
+func loadConfigFile(path, profile string) (settings, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	var profiles map[string]map[string]json.RawMessage
+	if rawProfiles, ok := raw[profilesKey]; ok {
+		err = json.Unmarshal(rawProfiles, &profiles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profiles in config file %s: %v", path, err)
+		}
+		delete(raw, profilesKey)
+	}
+
+	result, err := decodeSettings(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		return result, nil
+	}
+	rawProfile, ok := profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("no profile %s in config file %s", profile, path)
+	}
+	profileSettings, err := decodeSettings(rawProfile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %v", profile, err)
+	}
+	for name, value := range profileSettings {
+		result[name] = value
+	}
+
+	return result, nil
+}
+
+// parseEnviron converts environment of key=value strings to map
+func parseEnviron(environ []string) map[string]string {
+	result := make(map[string]string, len(environ))
+	for _, env := range environ {
+		if i := strings.IndexByte(env, '='); i >= 0 {
+			result[env[:i]] = env[i+1:]
+		}
+	}
+	return result
+}
+
+// envSettings returns settings of flags of fs given by environment variables
+func envSettings(fs *flag.FlagSet, env map[string]string) settings {
+	result := settings{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if value, ok := env[envName(f.Name)]; ok && !isMetaFlag(f.Name) {
+			result[f.Name] = value
+		}
+	})
+	return result
+}
+
+// applySettings sets flags of fs by s, except explicitly set ones
+func applySettings(fs *flag.FlagSet, s settings, explicit map[string]bool, source string) error {
+	for name, value := range s {
+		if fs.Lookup(name) == nil || isMetaFlag(name) {
+			return fmt.Errorf("unknown setting %s in %s", name, source)
+		}
+		if explicit[name] {
+			continue
+		}
+		err := fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid value of %s in %s: %v", name, source, err)
+		}
+	}
+	return nil
+}
+
+// loadSettings parses args into flags of fs and fills flags not given by args from config file and environment
+// Priority is flags, environment variables, profile of config file, config file and then defaults
+func loadSettings(fs *flag.FlagSet, args []string, environ []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	env := parseEnviron(environ)
+
+	// Config file and profile can be selected by environment too
+	path, profile := fs.Lookup("config").Value.String(), fs.Lookup("profile").Value.String()
+	if value, ok := env[envName("config")]; ok && !explicit["config"] {
+		path = value
+	}
+	if value, ok := env[envName("profile")]; ok && !explicit["profile"] {
+		profile = value
+	}
+
+	if path != "" {
+		var fileSettings settings
+		fileSettings, err = loadConfigFile(path, profile)
+		if err != nil {
+			return err
+		}
+		err = applySettings(fs, fileSettings, explicit, path)
+		if err != nil {
+			return err
+		}
+	} else if profile != "" {
+		return fmt.Errorf("profile %s is given without config file", profile)
+	}
+
+	return applySettings(fs, envSettings(fs, env), explicit, "environment")
+}
+
+// writeSettings writes effective settings of fs as JSON config file
+func writeSettings(fs *flag.FlagSet, w io.Writer) error {
+	// Keys are sorted by json package
+	values := make(map[string]interface{})
+	fs.VisitAll(func(f *flag.Flag) {
+		if isMetaFlag(f.Name) {
+			return
+		}
+		if getter, ok := f.Value.(flag.Getter); ok {
+			values[f.Name] = getter.Get()
+		} else {
+			values[f.Name] = f.Value.String()
+		}
+	})
+
+	content, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(content))
+	return err
+}
+
+// validateSettings checks settings up front, before any work is started
+func validateSettings() error {
+	if *k < 2 {
+		return fmt.Errorf("k cannot be less than 2")
+	}
+	if *n < 2 {
+		return fmt.Errorf("n cannot be less than 2")
+	}
+	if *incremental && (*k < sorter.MinIncrementalFanIn || *n < sorter.MinIncrementalFanIn) {
+		return fmt.Errorf("k and n cannot be less than %d in incremental sort", sorter.MinIncrementalFanIn)
+	}
+	if *chanBuf < 0 {
+		return fmt.Errorf("chan-buf cannot be negative")
+	}
+	if *processorNumber < 1 {
+		return fmt.Errorf("p cannot be less than 1")
+	}
+	if *readers < 1 {
+		return fmt.Errorf("r cannot be less than 1")
+	}
+	if *maxDepth < 0 {
+		return fmt.Errorf("max-depth cannot be negative")
+	}
+	if *maxRecord < 0 {
+		return fmt.Errorf("max-record cannot be negative")
+	}
+	if *ioBlockSize < 0 || *ioMemory < 0 || *tempQuota < 0 {
+		return fmt.Errorf("io-block, io-memory and temp-quota cannot be negative")
+	}
+	if *memoryLimit < 0 {
+		return fmt.Errorf("memory-limit cannot be negative")
+	}
+	if *memoryPressure < 0 || *memoryPressure > 100 {
+		return fmt.Errorf("memory-pressure should be a percent between 0 and 100")
+	}
+	if *partitions < 1 {
+		return fmt.Errorf("partitions cannot be less than 1")
+	}
+	if *sampleSize < *partitions {
+		return fmt.Errorf("sample-size cannot be less than partitions")
+	}
+	if _, err := parseTime(*modifiedSince); err != nil {
+		return fmt.Errorf("invalid modified-since time: %v", err)
+	}
+	_, err := newSortConfig()
+	return err
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestFlagSet creates flag set with some settings and meta flags of config
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("k", 4, "")
+	fs.Int("n", 2, "")
+	fs.Int64("io-memory", 64, "")
+	fs.Bool("v", false, "")
+	fs.String("t", "", "")
+	fs.String("config", "", "")
+	fs.String("profile", "", "")
+	return fs
+}
+
+func writeConfig(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	return file.Name()
+}
+
+func TestLoadSettings(t *testing.T) {
+	path := writeConfig(t, `{
+		"k": 100, "n": 10, "t": "/tmp/a", "v": true,
+		"profiles": {"big": {"k": 1000, "io-memory": "128"}}
+	}`)
+	defer func() {
+		_ = os.Remove(path)
+	}()
+
+	tests := []struct {
+		name     string
+		args     []string
+		environ  []string
+		expected map[string]string
+	}{
+		{"defaults", nil, nil, map[string]string{"k": "4", "n": "2", "v": "false"}},
+		{"config file", []string{"-config", path}, nil,
+			map[string]string{"k": "100", "n": "10", "t": "/tmp/a", "v": "true", "io-memory": "64"}},
+		{"profile", []string{"-config", path, "-profile", "big"}, nil,
+			map[string]string{"k": "1000", "n": "10", "io-memory": "128"}},
+		{"config from environment", nil, []string{"SORTTERMS_CONFIG=" + path, "SORTTERMS_PROFILE=big"},
+			map[string]string{"k": "1000", "n": "10"}},
+		{"environment over config", []string{"-config", path}, []string{"SORTTERMS_N=20", "SORTTERMS_IO_MEMORY=32"},
+			map[string]string{"k": "100", "n": "20", "io-memory": "32"}},
+		{"flags over environment", []string{"-config", path, "-n", "30"}, []string{"SORTTERMS_N=20"},
+			map[string]string{"k": "100", "n": "30"}},
+	}
+
+	for _, test := range tests {
+		fs := newTestFlagSet()
+		err := loadSettings(fs, test.args, test.environ)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for name, expected := range test.expected {
+			if value := fs.Lookup(name).Value.String(); value != expected {
+				t.Errorf("%s: %s is %s, but should be %s", test.name, name, value, expected)
+			}
+		}
+	}
+}
+
+func TestLoadSettings_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	configs := map[string]string{
+		"unknown":  `{"unknown": 1}`,
+		"invalid":  `{"k": "many"}`,
+		"object":   `{"k": {}}`,
+		"profiles": `{"profiles": {"small": {"k": 10}}}`,
+	}
+	tests := map[string][]string{
+		"unknown":  {"-config", filepath.Join(dir, "unknown")},
+		"invalid":  {"-config", filepath.Join(dir, "invalid")},
+		"object":   {"-config", filepath.Join(dir, "object")},
+		"profiles": {"-config", filepath.Join(dir, "profiles"), "-profile", "big"},
+		"missing":  {"-config", filepath.Join(dir, "missing")},
+		"profile":  {"-profile", "big"},
+	}
+	for name, content := range configs {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, args := range tests {
+		err = loadSettings(newTestFlagSet(), args, nil)
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestWriteSettings(t *testing.T) {
+	fs := newTestFlagSet()
+	err := loadSettings(fs, []string{"-k", "100", "-t", "/tmp/a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	err = writeSettings(fs, &buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Printed settings can be used as config file
+	path := writeConfig(t, buffer.String())
+	defer func() {
+		_ = os.Remove(path)
+	}()
+	loaded := newTestFlagSet()
+	err = loadSettings(loaded, []string{"-config", path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Lookup("k").Value.String() != "100" || loaded.Lookup("t").Value.String() != "/tmp/a" {
+		t.Errorf("settings are not same after loading printed settings: %s", buffer.String())
+	}
+
+	var values map[string]interface{}
+	err = json.Unmarshal(buffer.Bytes(), &values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := values["config"]; ok {
+		t.Error("config flag should not be printed")
+	}
+}
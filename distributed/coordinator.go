@@ -0,0 +1,231 @@
+package distributed
+
+import (
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"AID/solution/merger"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Coordinator assigns input files to Workers and merges their sorted results
+type Coordinator struct {
+	workers  []string // base URLs of workers
+	client   *http.Client
+	settings Settings // sent to workers to sort by them
+	cmp      *comparator.Comparator
+}
+
+// NewCoordinator creates new Coordinator entity which sends files to workers at base URLs,
+// workers sort by settings and their results are merged by cmp, which should be the comparator of settings
+func NewCoordinator(workers []string, settings Settings, cmp *comparator.Comparator) *Coordinator {
+	return &Coordinator{
+		workers:  workers,
+		client:   &http.Client{},
+		settings: settings,
+		cmp:      cmp,
+	}
+}
+
+// assign distributes files across workers greedily by size, biggest file goes to the least loaded worker
+func (c *Coordinator) assign(files []string) ([][]string, error) {
+	sizes := make(map[string]int64, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		sizes[file] = info.Size()
+	}
+
+	sorted := make([]string, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool { return sizes[sorted[i]] > sizes[sorted[j]] })
+
+	assignments := make([][]string, len(c.workers))
+	loads := make([]int64, len(c.workers))
+	for _, file := range sorted {
+		least := 0
+		for i := range loads {
+			if loads[i] < loads[least] {
+				least = i
+			}
+		}
+		assignments[least] = append(assignments[least], file)
+		loads[least] += sizes[file]
+	}
+
+	return assignments, nil
+}
+
+// stream is sorted result of a worker
+type stream struct {
+	worker string
+	body   io.ReadCloser
+	ch     chan []string
+	err    error // error in reading body, valid after ch is closed
+}
+
+// request sends files to worker and returns its sorted result stream
+func (c *Coordinator) request(ctx context.Context, worker string, files []string) (*stream, error) {
+	body, err := json.Marshal(SortRequest{Files: files, Settings: c.settings})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(worker, "/")+SortPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("worker %s failed: %s: %s", worker, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	return &stream{worker: worker, body: resp.Body, ch: make(chan []string)}, nil
+}
+
+// read puts lines of stream body in its channel in batches of helper.BatchSize
+func (s *stream) read(ctx context.Context) {
+	defer close(s.ch)
+	defer func() {
+		_ = s.body.Close()
+	}()
+
+	reader := bufio.NewReader(s.body)
+	batch := make([]string, 0, helper.BatchSize)
+	for {
+		line, err := helper.GetNextLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.err = fmt.Errorf("error in reading result of worker %s: %v", s.worker, err)
+			return
+		}
+		batch = append(batch, line)
+		if len(batch) < helper.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case s.ch <- batch:
+			batch = make([]string, 0, helper.BatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		select {
+		case <-ctx.Done():
+		case s.ch <- batch:
+		}
+	}
+}
+
+// Sort sorts files by workers in parallel and merges their results into file located at outputPath
+func (c *Coordinator) Sort(ctx context.Context, files []string, outputPath string) (err error) {
+	if len(c.workers) == 0 {
+		return fmt.Errorf("no worker is given")
+	}
+
+	assignments, err := c.assign(files)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Workers sort concurrently, a response arrives when its worker has finished sorting
+	type result struct {
+		s   *stream
+		err error
+	}
+	results := make(chan result, len(c.workers))
+	requests := 0
+	for i, worker := range c.workers {
+		if len(assignments[i]) == 0 {
+			continue
+		}
+		requests++
+		log.Infof("Assign %d files to worker %s", len(assignments[i]), worker)
+		go func(worker string, files []string) {
+			s, err := c.request(ctx, worker, files)
+			results <- result{s, err}
+		}(worker, assignments[i])
+	}
+
+	var streams []*stream
+	defer func() {
+		for _, s := range streams {
+			_ = s.body.Close()
+		}
+	}()
+	for i := 0; i < requests; i++ {
+		r := <-results
+		if r.err != nil {
+			if err == nil {
+				err = r.err
+				// Stop other workers
+				cancel()
+			}
+			continue
+		}
+		streams = append(streams, r.s)
+	}
+	if err != nil {
+		return err
+	}
+
+	chs := make([]<-chan []string, 0, len(streams))
+	for _, s := range streams {
+		go s.read(ctx)
+		chs = append(chs, s.ch)
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := output.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	err = merger.Merge(ctx, chs, output, c.cmp)
+	if err != nil {
+		return err
+	}
+
+	// A truncated stream looks like a shorter one, so errors are checked after merge
+	for _, s := range streams {
+		if s.err != nil {
+			return s.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package distributed
+
+import (
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"AID/solution/sorter"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// bytewise are settings of sorting lines byte-wise as they are
+var bytewise = Settings{Comparator: comparator.Bytewise.Name}
+
+// testConfig creates config of worker sorting by comparator of settings
+func testConfig(settings Settings) (sorter.Config, error) {
+	cmp, err := comparator.Get(settings.Comparator)
+	if err != nil {
+		return sorter.Config{}, err
+	}
+	return sorter.Config{
+		K:             10,
+		N:             3,
+		SortTransform: bundler.KeySortTransform(cmp),
+		Comparator:    cmp,
+	}, nil
+}
+
+// startWorkers starts count workers on localhost which read files under dir and returns their URLs
+func startWorkers(t *testing.T, dir string, count int) ([]string, func()) {
+	var urls []string
+	var servers []*httptest.Server
+	for i := 0; i < count; i++ {
+		tempPath := filepath.Join(dir, "worker"+strconv.Itoa(i))
+		if err := os.Mkdir(tempPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		worker, err := NewWorker(dir, []string{tempPath}, testConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(worker.Handler())
+		servers = append(servers, server)
+		urls = append(urls, server.URL)
+	}
+	return urls, func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+}
+
+// createInputs writes count files of random lines and returns their paths and all lines
+func createInputs(t *testing.T, dir string, count int) ([]string, []string) {
+	r := rand.New(rand.NewSource(1))
+	var files, lines []string
+	for i := 0; i < count; i++ {
+		var fileLines []string
+		for j := 0; j < 10+r.Intn(100); j++ {
+			fileLines = append(fileLines, "term "+strconv.Itoa(r.Intn(1000)))
+		}
+		file := filepath.Join(dir, "input"+strconv.Itoa(i))
+		err := ioutil.WriteFile(file, []byte(strings.Join(fileLines, "\n")+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+		lines = append(lines, fileLines...)
+	}
+	return files, lines
+}
+
+func TestCoordinator_Sort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	urls, stop := startWorkers(t, dir, 3)
+	defer stop()
+
+	files, lines := createInputs(t, dir, 7)
+	outputPath := filepath.Join(dir, "out.txt")
+
+	err = NewCoordinator(urls, bytewise, comparator.Bytewise).Sort(context.Background(), files, outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(lines)
+	expected := strings.Join(lines, "\n") + "\n"
+	if string(content) != expected {
+		t.Errorf("output is not sorted input, got %d bytes, expected %d bytes", len(content), len(expected))
+	}
+}
+
+func TestCoordinator_SortSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	urls, stop := startWorkers(t, dir, 2)
+	defer stop()
+
+	var files []string
+	for i, content := range []string{"beer\nApple\nBratwurst\n", "apricot\nBEEF\n"} {
+		file := filepath.Join(dir, "input"+strconv.Itoa(i))
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	outputPath := filepath.Join(dir, "out.txt")
+
+	// Workers sort by settings of coordinator rather than their own
+	settings := Settings{Comparator: comparator.CaseFold.Name}
+	err = NewCoordinator(urls, settings, comparator.CaseFold).Sort(context.Background(), files, outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Apple\napricot\nBEEF\nbeer\nBratwurst\n"
+	if string(content) != expected {
+		t.Errorf("output is %q, but should be %q", content, expected)
+	}
+}
+
+func TestWorker_FilesOutOfRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	if err = os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(dir, "secret")
+	if err = ioutil.WriteFile(outside, []byte("secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err = os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	worker, err := NewWorker(root, []string{dir}, testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{outside, filepath.Join(root, "..", "secret"), link} {
+		if _, err = worker.resolveFiles([]string{file}); err == nil {
+			t.Errorf("%s should be rejected as it is out of root", file)
+		}
+	}
+
+	inside := filepath.Join(root, "input")
+	if err = ioutil.WriteFile(inside, []byte("beer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = worker.resolveFiles([]string{inside}); err != nil {
+		t.Errorf("%s should be accepted: %v", inside, err)
+	}
+}
+
+func TestCoordinator_SortWorkerError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	urls, stop := startWorkers(t, dir, 2)
+	defer stop()
+
+	files, _ := createInputs(t, dir, 2)
+	// The worker cannot read a directory as input file
+	files = append(files, dir)
+
+	err = NewCoordinator(urls, bytewise, comparator.Bytewise).Sort(context.Background(), files, filepath.Join(dir, "out.txt"))
+	if err == nil {
+		t.Error("expected error of worker")
+	}
+}
+
+func TestWorker_ReadError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	if err = ioutil.WriteFile(input, []byte("beer\nwine\nwhiskey\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines longer than 4 bytes fail the read, so partial output shouldn't be returned
+	worker, err := NewWorker(dir, []string{dir}, func(settings Settings) (sorter.Config, error) {
+		cfg, err := testConfig(settings)
+		cfg.RecordLimit = helper.RecordLimit{MaxSize: 4, Policy: helper.FailRecord}
+		return cfg, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(worker.Handler())
+	defer server.Close()
+
+	body, err := json.Marshal(SortRequest{Files: []string{input}, Settings: bytewise})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL+SortPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status of request whose input can't be read is %d, but should be %d",
+			resp.StatusCode, http.StatusInternalServerError)
+	}
+}
+
+func TestCoordinator_assign(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distributed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var files []string
+	for i, size := range []int{70, 30, 20, 10, 5} {
+		file := filepath.Join(dir, strconv.Itoa(i))
+		if err = ioutil.WriteFile(file, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	assignments, err := NewCoordinator([]string{"a", "b"}, bytewise, comparator.Bytewise).assign(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assignments[0]) != 1 || assignments[0][0] != files[0] {
+		t.Errorf("expected the biggest file alone on the first worker, got %v", assignments[0])
+	}
+	if len(assignments[1]) != 4 {
+		t.Errorf("expected rest of files on the second worker, got %v", assignments[1])
+	}
+}
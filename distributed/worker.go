@@ -0,0 +1,192 @@
+package distributed
+
+import (
+	"AID/solution/helper"
+	"AID/solution/inputserializer"
+	"AID/solution/sorter"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SortPath is the HTTP path of sort requests served by Worker
+const SortPath = "/sort"
+
+// Settings define order and content of sorted lines, workers sort by the settings of Coordinator,
+// so their results are ordered as Coordinator merges them
+type Settings struct {
+	Comparator string   `json:"comparator"` // name of comparator, see comparator.Get
+	Blank      string   `json:"blank"`      // blank lines policy, see blank.GetPolicy
+	Normalize  string   `json:"normalize"`  // Unicode normalization form, empty means lines are not normalized
+	Transforms []string `json:"transforms"` // specs of line transforms, see bundler.GetTransforms
+}
+
+// SortRequest is sent by Coordinator to a Worker to sort files
+type SortRequest struct {
+	Files    []string `json:"files"`    // paths of input files, accessible by the worker under its root
+	Settings Settings `json:"settings"` // settings of Coordinator
+}
+
+// ConfigFunc creates sort config of Worker by settings of a request
+type ConfigFunc func(settings Settings) (sorter.Config, error)
+
+// Worker sorts files assigned by Coordinator by the local pipeline and streams the sorted result back
+type Worker struct {
+	root      string // resolved path of directory, only files under it are read
+	tempPaths []string
+	configure ConfigFunc
+}
+
+// NewWorker creates new Worker entity which reads files under root, stores temporary files under tempPaths
+// and sorts by config created by configure for each request
+func NewWorker(root string, tempPaths []string, configure ConfigFunc) (*Worker, error) {
+	if root == "" {
+		return nil, fmt.Errorf("root directory of worker is needed")
+	}
+	resolved, err := resolvePath(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Worker{root: resolved, tempPaths: tempPaths, configure: configure}, nil
+}
+
+// resolvePath returns absolute path of p without symbolic links
+func resolvePath(p string) (string, error) {
+	abs, err := filepath.Abs(filepath.Clean(p))
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// resolveFiles resolves paths of files and checks whether they are under root,
+// resolved paths are read so links can't point out of root
+func (w *Worker) resolveFiles(files []string) ([]string, error) {
+	resolved := make([]string, 0, len(files))
+	for _, file := range files {
+		p, err := resolvePath(file)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not under root directory of worker", file)
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved, nil
+}
+
+// Handler returns HTTP handler serving sort requests
+func (w *Worker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SortPath, w.handleSort)
+	return mux
+}
+
+func (w *Worker) handleSort(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SortRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("Sort request of %d files from %s", len(req.Files), r.RemoteAddr)
+
+	files, err := w.resolveFiles(req.Files)
+	if err != nil {
+		log.Warningf("Rejected sort request from %s: %v", r.RemoteAddr, err)
+		http.Error(rw, err.Error(), http.StatusForbidden)
+		return
+	}
+	cfg, err := w.configure(req.Settings)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Each request has its own directory under every temporary path
+	var jobDirs []string
+	defer func() {
+		for _, dir := range jobDirs {
+			_ = helper.CleanDir(dir)
+		}
+	}()
+	for _, tempPath := range w.tempPaths {
+		var dir string
+		dir, err = ioutil.TempDir(tempPath, "job")
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jobDirs = append(jobDirs, dir)
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	serializer := inputserializer.NewFileListSerializer(files)
+	serializer.SetBlankFilter(cfg.Blank)
+	serializer.SetRecordLimit(cfg.RecordLimit)
+	serializer.SetSkipBinary(cfg.SkipBinary)
+	// Sort is stopped if input can't be read by the policy of oversized records
+	serializer.SetErrorHandler(func(error) { cancel() })
+	readCh, err := serializer.GetSerializerCh(ctx)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outputPath := path.Join(jobDirs[0], "sorted")
+	err = sorter.Sort(ctx, readCh, jobDirs, outputPath, cfg)
+	if readErr := serializer.Err(); readErr != nil {
+		// Output of partial input is never returned
+		err = fmt.Errorf("error in reading input: %v", readErr)
+	} else if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		log.Errorf("error in sorting request from %s: %v", r.RemoteAddr, err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		_ = output.Close()
+	}()
+
+	info, err := output.Stat()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Content length lets the coordinator detect a truncated stream
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	_, err = io.Copy(rw, output)
+	if err != nil {
+		log.Errorf("error in streaming result to %s: %v", r.RemoteAddr, err)
+	}
+}
@@ -2,4 +2,7 @@ module AID/solution
 
 go 1.13
 
-require github.com/sirupsen/logrus v1.4.2
+require (
+	github.com/sirupsen/logrus v1.4.2
+	golang.org/x/text v0.3.2
+)
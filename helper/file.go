@@ -2,7 +2,11 @@ package helper
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
 	"os"
@@ -38,22 +42,124 @@ func WriteSliceToFile(path string, source []string) error {
 // GetNextLine get next line from reader
 // It handles big lines too
 func GetNextLine(reader *bufio.Reader) (line string, err error) {
+	line, _, err = GetNextLimitedLine(reader, RecordLimit{})
+	return
+}
+
+// RecordPolicy defines how records longer than maximum size are handled
+type RecordPolicy int
+
+const (
+	// TruncateRecord cuts record to maximum size
+	TruncateRecord RecordPolicy = iota
+	// SkipRecord skips whole record
+	SkipRecord
+	// FailRecord stops reading with ErrRecordTooLong
+	FailRecord
+)
+
+var recordPolicyNames = []string{"truncate", "skip", "fail"}
+
+func (p RecordPolicy) String() string {
+	if p < 0 || int(p) >= len(recordPolicyNames) {
+		return fmt.Sprintf("RecordPolicy(%d)", int(p))
+	}
+	return recordPolicyNames[p]
+}
+
+// GetRecordPolicy returns policy of oversized records by name: truncate, skip or fail
+func GetRecordPolicy(name string) (RecordPolicy, error) {
+	for i, policyName := range recordPolicyNames {
+		if name == policyName {
+			return RecordPolicy(i), nil
+		}
+	}
+	return TruncateRecord, fmt.Errorf("unknown policy of oversized records %s", name)
+}
+
+// RecordLimit limits size of records read by GetNextLimitedLine
+type RecordLimit struct {
+	MaxSize int          // maximum size of record in bytes, zero means unlimited
+	Policy  RecordPolicy // handling of records longer than MaxSize
+}
+
+// ErrRecordTooLong is returned by GetNextLimitedLine for record longer than maximum size by FailRecord policy
+var ErrRecordTooLong = errors.New("record is too long")
+
+// GetNextLimitedLine get next line from reader, at most limit.MaxSize bytes of line are kept in memory
+// oversized is true if line is longer than limit.MaxSize, then by limit.Policy line is either
+// truncated at a character boundary, or skipped and returned empty, or ErrRecordTooLong is returned
+// and the rest of line is not read
+func GetNextLimitedLine(reader *bufio.Reader, limit RecordLimit) (line string, oversized bool, err error) {
 
 	isPrefix := true
 	var chunk, buffer []byte
 	for isPrefix {
 		chunk, isPrefix, err = reader.ReadLine()
 		if err != nil {
+			if err == io.EOF && (len(buffer) > 0 || oversized) {
+				// File ends with a line which fills reader buffer and has no new line
+				err = nil
+				break
+			}
 			if err == io.EOF {
 				return
 			}
 			log.Error("error in reading", err)
 			return
 		}
+
+		if limit.MaxSize > 0 && len(buffer)+len(chunk) > limit.MaxSize {
+			if limit.Policy == FailRecord {
+				return "", true, ErrRecordTooLong
+			}
+			// Rest of line is read and dropped
+			if !oversized {
+				buffer = append(buffer, chunk[:limit.MaxSize-len(buffer)]...)
+			}
+			oversized = true
+			continue
+		}
 		buffer = append(buffer, chunk...)
 	}
 
+	if oversized {
+		return oversizedLine(buffer, limit), true, nil
+	}
 	line = string(buffer)
 
 	return
 }
+
+// oversizedLine returns what is kept of oversized line whose first limit.MaxSize bytes are buffer
+func oversizedLine(buffer []byte, limit RecordLimit) string {
+	if limit.Policy == SkipRecord {
+		return ""
+	}
+	// Partial character at the end is cut
+	end := len(buffer)
+	for i := 1; i <= utf8.UTFMax && i <= len(buffer); i++ {
+		if utf8.RuneStart(buffer[len(buffer)-i]) {
+			if !utf8.FullRune(buffer[len(buffer)-i:]) {
+				end = len(buffer) - i
+			}
+			break
+		}
+	}
+	return string(buffer[:end])
+}
+
+// BinaryCheckSize is the size of the first block of file which is checked for NUL bytes by IsBinary
+const BinaryCheckSize = 4096
+
+// IsBinary checks whether the first block of reader has NUL bytes, which text files don't have
+// Nothing is consumed from reader
+func IsBinary(reader *bufio.Reader) bool {
+	size := BinaryCheckSize
+	if size > reader.Size() {
+		size = reader.Size()
+	}
+	// Short files are peeked with EOF error
+	block, _ := reader.Peek(size)
+	return bytes.IndexByte(block, 0) != -1
+}
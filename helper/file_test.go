@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readLines reads all lines of content by limit
+func readLines(t *testing.T, content string, limit RecordLimit) (lines []string, oversized int, err error) {
+	// Small buffer makes lines span several chunks
+	reader := bufio.NewReaderSize(strings.NewReader(content), 16)
+	for {
+		line, isOversized, err := GetNextLimitedLine(reader, limit)
+		if err == io.EOF {
+			return lines, oversized, nil
+		}
+		if err != nil {
+			return lines, oversized, err
+		}
+		if isOversized {
+			oversized++
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestGetNextLimitedLine(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	content := "beer\n" + long + "\nbeeeeü\n" + long
+
+	lines, oversized, err := readLines(t, content, RecordLimit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"beer", long, "beeeeü", long}
+	if !reflect.DeepEqual(lines, expected) || oversized != 0 {
+		t.Errorf("unlimited lines are %q, %d oversized", lines, oversized)
+	}
+
+	lines, oversized, err = readLines(t, content, RecordLimit{MaxSize: 6, Policy: TruncateRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ü is not cut in the middle
+	expected = []string{"beer", "xxxxxx", "beeee", "xxxxxx"}
+	if !reflect.DeepEqual(lines, expected) || oversized != 3 {
+		t.Errorf("truncated lines are %q, %d oversized", lines, oversized)
+	}
+
+	lines, oversized, err = readLines(t, content, RecordLimit{MaxSize: 20, Policy: SkipRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"beer", "", "beeeeü", ""}
+	if !reflect.DeepEqual(lines, expected) || oversized != 2 {
+		t.Errorf("skipped lines are %q, %d oversized", lines, oversized)
+	}
+
+	lines, _, err = readLines(t, content, RecordLimit{MaxSize: 20, Policy: FailRecord})
+	if err != ErrRecordTooLong || !reflect.DeepEqual(lines, []string{"beer"}) {
+		t.Errorf("fail policy read %q with error %v", lines, err)
+	}
+}
+
+func TestGetRecordPolicy(t *testing.T) {
+	for _, p := range []RecordPolicy{TruncateRecord, SkipRecord, FailRecord} {
+		policy, err := GetRecordPolicy(p.String())
+		if err != nil || policy != p {
+			t.Errorf("policy of %s is %v, error %v", p, policy, err)
+		}
+	}
+
+	_, err := GetRecordPolicy("ignore")
+	if err == nil {
+		t.Error("unknown policy should return error")
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary(bufio.NewReader(strings.NewReader("beer\nmünchen\n"))) {
+		t.Error("text should not be binary")
+	}
+
+	reader := bufio.NewReader(strings.NewReader("\x7fELF\x02\x01\x01\x00\x00"))
+	if !IsBinary(reader) {
+		t.Error("content with NUL bytes should be binary")
+	}
+	// Nothing is consumed
+	line, err := GetNextLine(reader)
+	if err != nil || !strings.HasPrefix(line, "\x7fELF") {
+		t.Errorf("line after check is %q, error %v", line, err)
+	}
+}
@@ -0,0 +1,30 @@
+// +build !windows
+
+package helper
+
+import (
+	"syscall"
+)
+
+// FreeSpace returns number of bytes available to user on filesystem of path
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// SameFilesystem checks whether two paths are on the same filesystem
+func SameFilesystem(path1, path2 string) (bool, error) {
+	var stat1, stat2 syscall.Stat_t
+	if err := syscall.Stat(path1, &stat1); err != nil {
+		return false, err
+	}
+	if err := syscall.Stat(path2, &stat2); err != nil {
+		return false, err
+	}
+
+	return stat1.Dev == stat2.Dev, nil
+}
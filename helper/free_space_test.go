@@ -0,0 +1,34 @@
+// +build !windows
+
+package helper
+
+import (
+	"testing"
+)
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace("testData")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if free == 0 {
+		t.Error("free space of testData should not be zero")
+	}
+
+	_, err = FreeSpace("notExistsDir")
+	if err == nil {
+		t.Error("FreeSpace should return error on invalid path")
+	}
+}
+
+func TestSameFilesystem(t *testing.T) {
+	same, err := SameFilesystem("testData", ".")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !same {
+		t.Error("testData and its parent should be on the same filesystem")
+	}
+}
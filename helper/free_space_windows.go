@@ -0,0 +1,17 @@
+package helper
+
+import (
+	"errors"
+)
+
+var errNotSupported = errors.New("not supported on windows")
+
+// FreeSpace returns number of bytes available to user on filesystem of path
+func FreeSpace(path string) (uint64, error) {
+	return 0, errNotSupported
+}
+
+// SameFilesystem checks whether two paths are on the same filesystem
+func SameFilesystem(path1, path2 string) (bool, error) {
+	return false, errNotSupported
+}
@@ -1 +1,4 @@
 package helper
+
+// BatchSize is the number of lines are moved together through channels between stages
+const BatchSize = 1024
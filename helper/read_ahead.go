@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"io"
+)
+
+// block is a chunk of data read in background
+type block struct {
+	data []byte
+	err  error
+}
+
+// ReadAheadReader reads underlying reader in blocks by a background goroutine,
+// one block is filled while the other one is consumed (double buffering)
+type ReadAheadReader struct {
+	full    chan block    // filled blocks ready to be consumed
+	free    chan []byte   // consumed blocks ready to be filled
+	done    chan struct{} // closed to stop background reading
+	current []byte        // not consumed part of current block
+	buffer  []byte        // buffer of current block, to be given back after consumption
+	err     error         // error happened after current block
+}
+
+// NewReadAheadReader creates ReadAheadReader reading r in blocks of blockSize bytes
+// Close should be called to stop background reading
+func NewReadAheadReader(r io.Reader, blockSize int) *ReadAheadReader {
+	ra := &ReadAheadReader{
+		full: make(chan block, 1),
+		free: make(chan []byte, 2),
+		done: make(chan struct{}),
+	}
+	ra.free <- make([]byte, blockSize)
+	ra.free <- make([]byte, blockSize)
+
+	go ra.fill(r)
+
+	return ra
+}
+
+func (ra *ReadAheadReader) fill(r io.Reader) {
+	defer close(ra.full)
+	for {
+		// Stopping has priority over reading next block
+		select {
+		case <-ra.done:
+			return
+		default:
+		}
+
+		var buffer []byte
+		select {
+		case <-ra.done:
+			return
+		case buffer = <-ra.free:
+		}
+
+		n, err := io.ReadFull(r, buffer)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+
+		select {
+		case <-ra.done:
+			return
+		case ra.full <- block{buffer[:n], err}:
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read implements io.Reader
+func (ra *ReadAheadReader) Read(p []byte) (int, error) {
+	for len(ra.current) == 0 {
+		if ra.buffer != nil {
+			ra.free <- ra.buffer[:cap(ra.buffer)]
+			ra.buffer = nil
+		}
+
+		if ra.err != nil {
+			return 0, ra.err
+		}
+
+		b, ok := <-ra.full
+		if !ok {
+			return 0, io.ErrClosedPipe
+		}
+		ra.buffer, ra.current, ra.err = b.data, b.data, b.err
+	}
+
+	n := copy(p, ra.current)
+	ra.current = ra.current[n:]
+	return n, nil
+}
+
+// Close stops background reading, underlying reader is not closed
+func (ra *ReadAheadReader) Close() error {
+	close(ra.done)
+	return nil
+}
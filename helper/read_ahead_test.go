@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadAheadReader(t *testing.T) {
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	for _, blockSize := range []int{1, 7, 4096, 1 << 20} {
+		ra := NewReadAheadReader(iotest.HalfReader(bytes.NewReader(data)), blockSize)
+		result, err := ioutil.ReadAll(ra)
+		if err != nil {
+			t.Errorf("block size %d: %v", blockSize, err)
+		}
+		if !bytes.Equal(result, data) {
+			t.Errorf("block size %d: read data is not same as source", blockSize)
+		}
+		_ = ra.Close()
+	}
+}
+
+type failingReader struct {
+	remaining int
+}
+
+var errFailingReader = errors.New("failing reader")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, errFailingReader
+	}
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+	r.remaining -= len(p)
+	return len(p), nil
+}
+
+func TestReadAheadReader_Error(t *testing.T) {
+	ra := NewReadAheadReader(&failingReader{remaining: 10}, 4)
+	defer func() {
+		_ = ra.Close()
+	}()
+
+	result, err := ioutil.ReadAll(ra)
+	if err != errFailingReader {
+		t.Errorf("error is %v, but should be %v", err, errFailingReader)
+	}
+	if len(result) != 10 {
+		t.Errorf("read %d bytes before error, but should be 10", len(result))
+	}
+}
+
+func TestReadAheadReader_Close(t *testing.T) {
+	ra := NewReadAheadReader(bytes.NewReader(make([]byte, 100)), 1)
+	_ = ra.Close()
+
+	_, err := ioutil.ReadAll(ra)
+	if err == nil || err == io.EOF {
+		t.Errorf("reading closed reader should fail, but error is %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package inputserializer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// FileListSerializer implements serializing a list of input files
+type FileListSerializer struct {
+	paths []string
+	lineReader
+}
+
+// NewFileListSerializer creates new FileListSerializer entity to serialize files located at paths in order
+func NewFileListSerializer(paths []string) *FileListSerializer {
+	return &FileListSerializer{paths: paths}
+}
+
+// GetSerializerCh creates single reader to read content of all files one after another
+// returns error if one of files is not a regular file
+func (f *FileListSerializer) GetSerializerCh(ctx context.Context) (<-chan []string, error) {
+	for _, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", path)
+		}
+	}
+
+	ch := make(chan []string)
+
+	go func() {
+		defer close(ch)
+		for _, path := range f.paths {
+			if f.readFile(ctx, path, nil, ch) != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,36 @@
+package inputserializer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileListSerializer(t *testing.T) {
+	root := createTree(t, []string{"a.log", "b.log", "sub/c.log"})
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	files, err := NewDirSerializer(root).Files()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []string{filepath.Join(root, "a.log"), filepath.Join(root, "b.log"), filepath.Join(root, "sub", "c.log")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("files are %v, but should be %v", files, expected)
+	}
+
+	result := readAll(t, NewFileListSerializer(files[1:]))
+	if !reflect.DeepEqual(result, []string{"b.log", "sub/c.log"}) {
+		t.Errorf("result is %v, but should be [b.log sub/c.log]", result)
+	}
+
+	_, err = NewFileListSerializer([]string{root}).GetSerializerCh(context.Background())
+	if err == nil {
+		t.Error("serializing a directory should return error")
+	}
+}
@@ -1,19 +1,23 @@
 package inputserializer
 
 import (
-	"AID/solution/helper"
-	"bufio"
+	"AID/solution/record"
 	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
 	"io"
 	"os"
-	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // DirSerializer implements serializing input file(s) under directory
 type DirSerializer struct {
-	path string
+	path    string
+	filter  Filter
+	readers int  // number of files read concurrently
+	origins bool // tag lines by their origins
+	lineReader
 }
 
 // NewDirSerializer creates new DirSerializer entity to serialized file(s) located under path directory
@@ -21,13 +25,80 @@ func NewDirSerializer(path string) *DirSerializer {
 	return &DirSerializer{path: path}
 }
 
-// GetSerializerCh creates single reader to read content of all files in input directory
+// NewFilteredDirSerializer creates new DirSerializer entity to serialized file(s) located under path directory
+// which pass the filter
+func NewFilteredDirSerializer(path string, filter Filter) *DirSerializer {
+	return &DirSerializer{path: path, filter: filter}
+}
+
+// SetReaders sets number of files are read concurrently, lines of files read
+// concurrently are interleaved in serializer channel
+func (f *DirSerializer) SetReaders(readers int) {
+	f.readers = readers
+}
+
+// SetOrigins sets whether lines are tagged by their origins (see record package),
+// files are indexed in walk order, so origins are same however many readers are used
+func (f *DirSerializer) SetOrigins(origins bool) {
+	f.origins = origins
+}
+
+// tagger returns tag function of lines of file with index, nil if lines are not tagged
+func (f *DirSerializer) tagger(index int) lineTagger {
+	if !f.origins {
+		return nil
+	}
+	return func(line string, number uint64) string {
+		return record.Tag(line, uint32(index), number)
+	}
+}
+
+// Files returns paths of files under input directory which pass the filter
+func (f *DirSerializer) Files() (paths []string, err error) {
+	err = f.filter.validate()
+	if err != nil {
+		return
+	}
+
+	err = f.filter.walk(f.path, func(path string, info os.FileInfo) error {
+		paths = append(paths, path)
+		return nil
+	})
+	return
+}
+
+// InputSize returns total size of files under input directory which pass the filter
+func (f *DirSerializer) InputSize() (size int64, err error) {
+	err = f.filter.validate()
+	if err != nil {
+		return
+	}
+
+	err = f.filter.walk(f.path, func(path string, info os.FileInfo) error {
+		size += info.Size()
+		return nil
+	})
+	return
+}
+
+// walkFailed records err which stopped walk of input directory, so it is returned by Err
+// and the sort fails rather than keeping output of partial input
+func (f *DirSerializer) walkFailed(err error) {
+	if err == nil || err == io.EOF || err == f.Err() {
+		// Readers stopped by ctx or by their own error which is already recorded
+		return
+	}
+	log.Errorf("error in walking %s: %v", f.path, err)
+	f.setErr(err)
+}
+
+// GetSerializerCh creates reader(s) to read content of all files in input directory
 // params
 // root input directory root path
 // returns
-// res a read-only string channel, one string for each line in input files
+// res a read-only channel of batches, one string in batch for each line in input files
 // err error
-func (f *DirSerializer) GetSerializerCh(ctx context.Context) (<-chan string, error) {
+func (f *DirSerializer) GetSerializerCh(ctx context.Context) (<-chan []string, error) {
 
 	// Check whether path exists!
 	fileInfo, err := os.Stat(f.path)
@@ -40,65 +111,64 @@ func (f *DirSerializer) GetSerializerCh(ctx context.Context) (<-chan string, err
 		return nil, err
 	}
 
-	ch := make(chan string)
+	err = f.filter.validate()
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		defer close(ch)
-		err := filepath.Walk(f.path, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				log.Warningf("Error in reading %s: %v", path, err)
-				return nil // Don't stop processing next files
-			}
+	ch := make(chan []string)
 
-			if info.IsDir() {
-				// Their contents will be processed
-				log.Infof("Content of files under directory %s will be serialized", path)
-				return nil
-			}
+	if f.readers <= 1 {
+		go func() {
+			defer close(ch)
+			index := 0
+			err := f.filter.walk(f.path, func(path string, info os.FileInfo) error {
+				index++
+				return f.readFile(ctx, path, f.tagger(index-1), ch)
+			})
+			f.walkFailed(err)
+		}()
 
-			if !info.Mode().IsRegular() {
-				log.Warningf("%s is not a regular file, is not reade\n", path)
-				return nil // Don't stop processing next files
-			}
+		return ch, nil
+	}
 
-			file, err := os.Open(path)
-			if err != nil {
-				log.Warningf("Error in opening %s: %v", path, err)
-				return nil // Don't stop processing next files
+	// Walk feeds file paths to a bounded pool of readers, all write to the same channel
+	type indexedPath struct {
+		path  string
+		index int // index of file in walk order
+	}
+	pathCh := make(chan indexedPath)
+	go func() {
+		defer close(pathCh)
+		index := 0
+		err := f.filter.walk(f.path, func(path string, info os.FileInfo) error {
+			select {
+			case <-ctx.Done():
+				return io.EOF // Return error (EOF) to stop walk from processing next files
+			case pathCh <- indexedPath{path, index}:
 			}
-			defer func() {
-				err = file.Close()
-				if err != nil {
-					log.Errorf("error in closding fil %s: %v", path, err)
-				}
-			}()
-
-			log.Debugf("Serialize content of: %s", path)
-
-			reader := bufio.NewReader(file)
-			var line string
-
-			for {
-				line, err = helper.GetNextLine(reader)
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					log.Errorf("error in reading file %s: %v", path, err)
-				}
-				select {
-				case <-ctx.Done():
-					return io.EOF // Return error (EOF) to stop filepath walk from processing next files
+			index++
+			return nil
+		})
+		f.walkFailed(err)
+	}()
 
-				case ch <- line:
+	var wg sync.WaitGroup
+	wg.Add(f.readers)
+	for i := 0; i < f.readers; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range pathCh {
+				if f.readFile(ctx, p.path, f.tagger(p.index), ch) != nil {
+					return
 				}
 			}
+		}()
+	}
 
-			return nil
-		})
-		if err != nil {
-			fmt.Println(err)
-		}
+	go func() {
+		wg.Wait()
+		close(ch)
 	}()
 
 	return ch, nil
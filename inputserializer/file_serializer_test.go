@@ -3,12 +3,22 @@ package inputserializer
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"AID/solution/blank"
 	"AID/solution/helper"
+	"AID/solution/record"
 )
 
 var update = flag.Bool("update", false, "update .golden files")
@@ -25,8 +35,8 @@ func TestSampleInput(t *testing.T) {
 	}
 
 	var result []string
-	for s := range ch {
-		result = append(result, s)
+	for batch := range ch {
+		result = append(result, batch...)
 	}
 
 	if *update {
@@ -71,3 +81,251 @@ func TestSampleInput(t *testing.T) {
 		}
 	}
 }
+
+// createTree creates files with content equal to their relative path under a temporary directory
+func createTree(t *testing.T, files []string) string {
+	root, err := ioutil.TempDir("", "serializer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(f))
+		err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filePath, []byte(f+"\n"), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func readAll(t *testing.T, serializer InputSerializer) []string {
+	ch, err := serializer.GetSerializerCh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for batch := range ch {
+		result = append(result, batch...)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestFilteredDirSerializer(t *testing.T) {
+	root := createTree(t, []string{
+		".DS_Store",
+		"a.log",
+		"b.log.tmp",
+		"sub/c.log",
+		"sub/.hidden.log",
+		"sub/deep/d.log",
+		"skip/e.log",
+	})
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	tests := []struct {
+		filter   Filter
+		expected []string
+	}{
+		{Filter{}, []string{".DS_Store", "a.log", "b.log.tmp", "skip/e.log", "sub/.hidden.log", "sub/c.log", "sub/deep/d.log"}},
+		{Filter{Include: []string{"*.log"}}, []string{"a.log", "skip/e.log", "sub/.hidden.log", "sub/c.log", "sub/deep/d.log"}},
+		{Filter{Exclude: []string{".*", "*.tmp", "skip"}}, []string{"a.log", "sub/c.log", "sub/deep/d.log"}},
+		{Filter{Exclude: []string{"sub/deep"}}, []string{".DS_Store", "a.log", "b.log.tmp", "skip/e.log", "sub/.hidden.log", "sub/c.log"}},
+		{Filter{Include: []string{"*.log"}, MaxDepth: 1}, []string{"a.log"}},
+		{Filter{Include: []string{"*.log"}, MaxDepth: 2}, []string{"a.log", "skip/e.log", "sub/.hidden.log", "sub/c.log"}},
+		{Filter{ModifiedSince: time.Now().Add(time.Hour)}, nil},
+	}
+
+	for i, test := range tests {
+		result := readAll(t, NewFilteredDirSerializer(root, test.filter))
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("case %d: result is %v, but should be %v", i, result, test.expected)
+		}
+	}
+
+	size, err := NewFilteredDirSerializer(root, Filter{Include: []string{"*.log"}, MaxDepth: 1}).InputSize()
+	if err != nil {
+		t.Error(err)
+	}
+	if size != int64(len("a.log\n")) {
+		t.Errorf("input size is %d, but should be %d", size, len("a.log\n"))
+	}
+
+	_, err = NewFilteredDirSerializer(root, Filter{Include: []string{"["}}).GetSerializerCh(context.Background())
+	if err == nil {
+		t.Error("invalid glob pattern should return error")
+	}
+}
+
+func TestFilteredDirSerializer_Symlinks(t *testing.T) {
+	root := createTree(t, []string{"a.log", "sub/b.log"})
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	// Loop back to root and link to a file
+	err := os.Symlink(root, filepath.Join(root, "sub", "loop"))
+	if err != nil {
+		t.Skipf("unable to create symbolic link: %v", err)
+	}
+	err = os.Symlink(filepath.Join(root, "a.log"), filepath.Join(root, "sub", "link.log"))
+	if err != nil {
+		t.Skipf("unable to create symbolic link: %v", err)
+	}
+
+	result := readAll(t, NewFilteredDirSerializer(root, Filter{}))
+	expected := []string{"a.log", "sub/b.log"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result without following links is %v, but should be %v", result, expected)
+	}
+
+	result = readAll(t, NewFilteredDirSerializer(root, Filter{FollowSymlinks: true}))
+	expected = []string{"a.log", "a.log", "sub/b.log"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result with following links is %v, but should be %v", result, expected)
+	}
+}
+
+func TestDirSerializer_Readers(t *testing.T) {
+	var files []string
+	for i := 0; i < 20; i++ {
+		files = append(files, fmt.Sprintf("sub%d/file%02d.log", i%3, i))
+	}
+	root := createTree(t, files)
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	sort.Strings(files)
+	for _, readers := range []int{1, 4, 30} {
+		serializer := NewDirSerializer(root)
+		serializer.SetReaders(readers)
+		result := readAll(t, serializer)
+		if !reflect.DeepEqual(result, files) {
+			t.Errorf("result with %d readers is %v, but should be %v", readers, result, files)
+		}
+	}
+}
+
+func TestDirSerializer_Origins(t *testing.T) {
+	files := []string{"b.log", "a/c.log", "a.log"}
+	root := createTree(t, files)
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	// Files are indexed in walk order whatever number of readers is
+	expected := []string{
+		record.Tag("a/c.log", 0, 0),
+		record.Tag("a.log", 1, 0),
+		record.Tag("b.log", 2, 0),
+	}
+	sort.Strings(expected)
+	for _, readers := range []int{1, 3} {
+		serializer := NewDirSerializer(root)
+		serializer.SetReaders(readers)
+		serializer.SetOrigins(true)
+		result := readAll(t, serializer)
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("result with %d readers is %v, but should be %v", readers, result, expected)
+		}
+	}
+}
+
+func TestDirSerializer_BlankFilter(t *testing.T) {
+	root := createTree(t, []string{"a.log"})
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+	err := ioutil.WriteFile(filepath.Join(root, "a.log"), []byte("\nbeer  \n \t\nbeer\n\n"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := blank.NewFilter(blank.Drop)
+	serializer := NewDirSerializer(root)
+	serializer.SetBlankFilter(filter)
+	result := readAll(t, serializer)
+	expected := []string{"beer", "beer  "}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result of dropping blank lines is %q, but should be %q", result, expected)
+	}
+	if filter.Blank() != 3 {
+		t.Errorf("%d blank lines are dropped, but should be 3", filter.Blank())
+	}
+}
+
+func TestDirSerializer_RecordLimit(t *testing.T) {
+	root := createTree(t, []string{"a.log", "b.bin"})
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+	err := ioutil.WriteFile(filepath.Join(root, "a.log"), []byte("beer\n"+strings.Repeat("x", 5000)+"\nwurst\n"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(root, "b.bin"), []byte("\x7fELF\x00\x00\nbeer\n"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serializer := NewDirSerializer(root)
+	serializer.SetSkipBinary(true)
+	serializer.SetRecordLimit(helper.RecordLimit{MaxSize: 10, Policy: helper.SkipRecord})
+	result := readAll(t, serializer)
+	expected := []string{"beer", "wurst"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result is %q, but should be %q", result, expected)
+	}
+	stats := serializer.Stats()
+	skipped := []SkippedFile{{Path: filepath.Join(root, "b.bin"), Reason: "binary"}}
+	if !reflect.DeepEqual(stats.SkippedFiles, skipped) || stats.SkippedRecords != 1 || stats.TruncatedRecords != 0 {
+		t.Errorf("stats are %+v", stats)
+	}
+
+	// Reading is stopped by the first oversized record
+	var handled error
+	serializer = NewDirSerializer(root)
+	serializer.SetSkipBinary(true)
+	serializer.SetRecordLimit(helper.RecordLimit{MaxSize: 10, Policy: helper.FailRecord})
+	serializer.SetErrorHandler(func(err error) { handled = err })
+	result = readAll(t, serializer)
+	for _, line := range result {
+		if line != "beer" {
+			t.Errorf("%q is read after oversized record", line)
+		}
+	}
+	if serializer.Err() == nil || handled != serializer.Err() {
+		t.Errorf("error is %v, handled error is %v", serializer.Err(), handled)
+	}
+}
+
+func TestDirSerializer_WalkFailed(t *testing.T) {
+	serializer := NewDirSerializer("input")
+	var handled error
+	serializer.SetErrorHandler(func(err error) { handled = err })
+
+	// Stopping readers by ctx is not a failure
+	serializer.walkFailed(io.EOF)
+	if serializer.Err() != nil {
+		t.Errorf("error is %v, but should be nil", serializer.Err())
+	}
+
+	walkErr := errors.New("walk failed")
+	serializer.walkFailed(walkErr)
+	if serializer.Err() != walkErr {
+		t.Errorf("error is %v, but should be %v", serializer.Err(), walkErr)
+	}
+	if handled != walkErr {
+		t.Errorf("handled error is %v, but should be %v", handled, walkErr)
+	}
+}
@@ -4,5 +4,5 @@ import "context"
 
 // InputSerializer common interface for all inputserializer modules
 type InputSerializer interface {
-	GetSerializerCh(ctx context.Context) (<-chan string, error)
+	GetSerializerCh(ctx context.Context) (<-chan []string, error)
 }
@@ -0,0 +1,219 @@
+package inputserializer
+
+import (
+	"AID/solution/blank"
+	"AID/solution/helper"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SkippedFile is an input file which is not read, with the reason
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Stats summarizes reading of input files
+type Stats struct {
+	Files            int           `json:"files"`             // number of files read
+	Bytes            int64         `json:"bytes"`             // total size of files read
+	Lines            int64         `json:"lines"`             // number of lines read, including dropped ones
+	SkippedFiles     []SkippedFile `json:"skipped_files"`     // files which are not read, e.g. binary ones
+	TruncatedRecords int64         `json:"truncated_records"` // number of records which are cut to maximum record size
+	SkippedRecords   int64         `json:"skipped_records"`   // number of records which are skipped for being longer than maximum record size
+}
+
+// lineReader reads lines of input files by options shared by serializers,
+// it is safe for concurrent use
+type lineReader struct {
+	blank      *blank.Filter      // filter of blank lines, nil means lines are kept as they are
+	limit      helper.RecordLimit // limit of records size
+	skipBinary bool               // skip files which have NUL bytes in their first block
+	onError    func(err error)    // called on the first error which stops reading
+
+	mutex sync.Mutex
+	stats Stats
+	err   error
+}
+
+// SetBlankFilter sets filter which applies blank lines policy to lines when they are read
+func (r *lineReader) SetBlankFilter(filter *blank.Filter) {
+	r.blank = filter
+}
+
+// SetRecordLimit sets maximum size of records and how longer ones are handled
+func (r *lineReader) SetRecordLimit(limit helper.RecordLimit) {
+	r.limit = limit
+}
+
+// SetSkipBinary sets whether files which have NUL bytes in their first block are skipped
+func (r *lineReader) SetSkipBinary(skipBinary bool) {
+	r.skipBinary = skipBinary
+}
+
+// SetErrorHandler sets f to be called on the first error which stops reading, e.g. an oversized record
+// by helper.FailRecord policy, so the consumer of lines can be stopped
+func (r *lineReader) SetErrorHandler(f func(err error)) {
+	r.onError = f
+}
+
+// Stats returns summary of reading input files
+func (r *lineReader) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := r.stats
+	stats.SkippedFiles = append([]SkippedFile(nil), r.stats.SkippedFiles...)
+	return stats
+}
+
+// Err returns the first error which stopped reading
+func (r *lineReader) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+func (r *lineReader) setErr(err error) {
+	r.mutex.Lock()
+	first := r.err == nil
+	if first {
+		r.err = err
+	}
+	r.mutex.Unlock()
+
+	if first && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *lineReader) skipFile(path, reason string) {
+	log.Warningf("Skip %s: %s", path, reason)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.SkippedFiles = append(r.stats.SkippedFiles, SkippedFile{Path: path, Reason: reason})
+}
+
+func (r *lineReader) countFile(file *os.File) {
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.Files++
+	r.stats.Bytes += size
+}
+
+func (r *lineReader) countLines(lines uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.Lines += int64(lines)
+}
+
+func (r *lineReader) countOversized(path string, number uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.limit.Policy == helper.SkipRecord {
+		log.Warningf("Skip line %d of %s longer than %d bytes", number, path, r.limit.MaxSize)
+		r.stats.SkippedRecords++
+	} else {
+		r.stats.TruncatedRecords++
+	}
+}
+
+// lineTagger tags line with its number in file
+type lineTagger func(line string, number uint64) string
+
+// readFile puts lines of file located at path in ch in batches of helper.BatchSize
+// lines are tagged by tag unless it is nil
+// returns io.EOF if ctx is done before reaching end of file, or the error which stopped reading
+func (r *lineReader) readFile(ctx context.Context, path string, tag lineTagger, ch chan<- []string) error {
+	// Other files are not read after an error
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		r.skipFile(path, err.Error())
+		return nil // Don't stop processing next files
+	}
+	defer func() {
+		err = file.Close()
+		if err != nil {
+			log.Errorf("error in closding fil %s: %v", path, err)
+		}
+	}()
+
+	log.Debugf("Serialize content of: %s", path)
+
+	reader := bufio.NewReader(file)
+	if r.skipBinary && helper.IsBinary(reader) {
+		r.skipFile(path, "binary")
+		return nil
+	}
+	r.countFile(file)
+
+	var line string
+	var oversized bool
+	var number uint64
+	defer func() {
+		r.countLines(number)
+	}()
+	batch := make([]string, 0, helper.BatchSize)
+
+	for {
+		line, oversized, err = helper.GetNextLimitedLine(reader, r.limit)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			if err == helper.ErrRecordTooLong {
+				err = fmt.Errorf("line %d of %s is longer than %d bytes", number+1, path, r.limit.MaxSize)
+				r.setErr(err)
+				return err
+			}
+			log.Errorf("error in reading file %s: %v", path, err)
+		}
+		number++
+		if oversized {
+			r.countOversized(path, number)
+			if r.limit.Policy == helper.SkipRecord {
+				continue
+			}
+		}
+		if r.blank != nil && !r.blank.Keep(line) {
+			continue
+		}
+		if tag != nil {
+			line = tag(line, number-1)
+		}
+		batch = append(batch, line)
+		if len(batch) < helper.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return io.EOF // Return error (EOF) to stop walk from processing next files
+
+		case ch <- batch:
+			batch = make([]string, 0, helper.BatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		select {
+		case <-ctx.Done():
+			return io.EOF
+		case ch <- batch:
+		}
+	}
+
+	return nil
+}
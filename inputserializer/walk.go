@@ -0,0 +1,141 @@
+package inputserializer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Filter defines which files under input directory are serialized
+type Filter struct {
+	Include        []string  // glob patterns, only matched files are read (all files if empty)
+	Exclude        []string  // glob patterns, matched files and directories are skipped
+	MaxDepth       int       // maximum depth of recursion, 1 means only files of root directory, 0 means unlimited
+	FollowSymlinks bool      // follow symbolic links to files and directories
+	ModifiedSince  time.Time // skip files modified before this time, zero value means no limit
+}
+
+// validate checks whether filter patterns are well-formed
+func (fl *Filter) validate() error {
+	for _, patterns := range [][]string{fl.Include, fl.Exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern %q: %v", p, err)
+			}
+		}
+	}
+
+	if fl.MaxDepth < 0 {
+		return fmt.Errorf("max depth %d cannot be negative", fl.MaxDepth)
+	}
+
+	return nil
+}
+
+// matchAny checks whether name or relative path (slash separated) matches one of patterns
+func matchAny(patterns []string, relPath, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, relPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFunc is called for every regular file passed the filter
+// returning an error stops the walk
+type walkFunc func(path string, info os.FileInfo) error
+
+// walk goes through files under root in lexical order and calls fn for the ones passed the filter
+func (fl *Filter) walk(root string, fn walkFunc) error {
+	visited := make(map[string]bool)
+	return fl.walkDir(root, "", 0, visited, fn)
+}
+
+func (fl *Filter) walkDir(dirPath, relPath string, depth int, visited map[string]bool, fn walkFunc) error {
+	// Resolved path is used to detect symbolic link loops
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil {
+		log.Warningf("Error in resolving %s: %v", dirPath, err)
+		return nil // Don't stop processing next files
+	}
+	if visited[realPath] {
+		log.Warningf("%s is already visited (symbolic link loop), is not read again", dirPath)
+		return nil
+	}
+	visited[realPath] = true
+
+	// Their contents will be processed
+	log.Infof("Content of files under directory %s will be serialized", dirPath)
+
+	infos, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		log.Warningf("Error in reading %s: %v", dirPath, err)
+		return nil // Don't stop processing next files
+	}
+
+	for _, info := range infos {
+		filePath := filepath.Join(dirPath, info.Name())
+		fileRelPath := path.Join(relPath, info.Name())
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !fl.FollowSymlinks {
+				log.Warningf("%s is not a regular file, is not read", filePath)
+				continue
+			}
+
+			info, err = os.Stat(filePath)
+			if err != nil {
+				log.Warningf("Error in following symbolic link %s: %v", filePath, err)
+				continue
+			}
+		}
+
+		if matchAny(fl.Exclude, fileRelPath, info.Name()) {
+			log.Debugf("%s is excluded", filePath)
+			continue
+		}
+
+		if info.IsDir() {
+			if fl.MaxDepth == 0 || depth+1 < fl.MaxDepth {
+				err = fl.walkDir(filePath, fileRelPath, depth+1, visited, fn)
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Debugf("%s is deeper than max depth %d, is not read", filePath, fl.MaxDepth)
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			log.Warningf("%s is not a regular file, is not read", filePath)
+			continue
+		}
+
+		if len(fl.Include) > 0 && !matchAny(fl.Include, fileRelPath, info.Name()) {
+			log.Debugf("%s is not included", filePath)
+			continue
+		}
+
+		if !fl.ModifiedSince.IsZero() && info.ModTime().Before(fl.ModifiedSince) {
+			log.Debugf("%s is modified before %s, is not read", filePath, fl.ModifiedSince)
+			continue
+		}
+
+		err = fn(filePath, info)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
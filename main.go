@@ -1,38 +1,87 @@
 package main
 
 import (
+	"AID/solution/blank"
 	"AID/solution/bundler"
-	"AID/solution/merger"
-	"AID/solution/tempstorage"
+	"AID/solution/comparator"
+	"AID/solution/distributed"
+	"AID/solution/helper"
+	"AID/solution/sorter"
 	"context"
 	"flag"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"runtime"
-	"sync"
+	"strings"
 	"syscall"
 	"time"
 
 	"AID/solution/inputserializer"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
 	inputPath       = flag.String("i", "inputserializer/testData/input", "input directory path")
-	tempPath        = flag.String("t", "", "temporary storage path")
+	tempPath        = flag.String("t", "", "comma separated temporary storage paths, runs are striped across them")
 	outputPath      = flag.String("o", "out.txt", "result path")
 	logPath         = flag.String("l", "", "log file path")
 	isLogVerbose    = flag.Bool("v", false, "verbose mode")
 	processorNumber = flag.Int("p", runtime.NumCPU(), "number of processor to use")
-	k               = flag.Int("k", 4, "available memory")
+	k               = flag.Int("k", 4, "available memory in number of lines of each bundle")
 	n               = flag.Int("n", 5000, "limit number of open files")
+	chanBuf         = flag.Int("chan-buf", 0, "size of temporary files channel buffers in batches, 0 means derived from k and n")
+	include         = flag.String("include", "", "comma separated glob patterns of input files to read")
+	exclude         = flag.String("exclude", "", "comma separated glob patterns of input files and directories to skip")
+	maxDepth        = flag.Int("max-depth", 0, "maximum depth of input directory recursion, 0 means unlimited")
+	followSymlinks  = flag.Bool("follow-symlinks", false, "follow symbolic links in input directory")
+	modifiedSince   = flag.String("modified-since", "", "only read input files modified since this time (2006-01-02 or RFC3339)")
+	readers         = flag.Int("r", 1, "number of input files to read concurrently")
+	useArena        = flag.Bool("arena", false, "keep bundle lines in a single reused memory slab")
+	sortAlgorithm   = flag.String("sort", "quick", "in-memory sort algorithm of bundles: quick or radix")
+	comparatorName  = flag.String("compare", "bytewise", "order of lines: bytewise, fold (Unicode case folding), collate (Unicode root collation) or collate:<locale>, e.g. collate:de")
+	normalize       = flag.String("normalize", "", "Unicode normalization form of lines: nfc or nfkc, empty means lines are not normalized")
+	blankPolicy     = flag.String("blank", "keep", "policy of blank lines: keep, drop, trim (compare lines without leading and trailing white space, but keep them) or count (report number of blank and padded lines)")
+	maxRecord       = flag.Int("max-record", 0, "maximum size of input lines in bytes, 0 means unlimited")
+	oversized       = flag.String("oversized", "truncate", "policy of input lines longer than max-record: truncate, skip or fail")
+	skipBinary      = flag.Bool("skip-binary", false, "skip input files which have NUL bytes in their first block")
+	transform       = flag.String("transform", "", "comma separated transforms of lines applied in order after normalization: trim, lower, collapse, strip-punct, drop-empty, nfc, nfkc, drop-regex:<pattern> or stopwords:<file>, arguments may have commas unless a transform name follows them")
+	ioBlockSize     = flag.Int("io-block", 0, "size of temporary files read-ahead and write buffers in bytes, 0 means derived from io-memory")
+	ioMemory        = flag.Int64("io-memory", 64, "memory budget of temporary files buffers in MB, 0 means default buffers without read-ahead")
+	tempQuota       = flag.Int64("temp-quota", 0, "limit of temporary files total size in MB, 0 means unlimited")
+	skipPreflight   = flag.Bool("skip-preflight", false, "skip checking free space of temporary and output paths before sort")
+	partitions      = flag.Int("partitions", 1, "number of key ranges sorted and merged in parallel")
+	sampleSize      = flag.Int("sample-size", 10000, "number of keys sampled from input to pick partition splitters")
+	count           = flag.Bool("count", false, "write distinct lines with their counts separated by tab")
+	incremental     = flag.Bool("incremental", false, "sort only input and merge it with the previous output")
+	stable          = flag.Bool("stable", false, "keep input order of lines with equal keys, files are ordered by path")
 )
 
-func init() {
-	flag.Parse()
+// splitList splits comma separated flag value, empty value results in empty list
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// parseTime parses date or RFC3339 time flag value, empty value results in zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
 
+// setupLogging configures logger by flags
+func setupLogging() {
 	log.SetFormatter(&log.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
@@ -52,32 +101,184 @@ func init() {
 			log.SetOutput(io.MultiWriter(lf, os.Stdout))
 		}
 	}
+}
 
-	runtime.GOMAXPROCS(*processorNumber)
+// flagSettings returns settings of order and content of sorted lines from flags
+func flagSettings() distributed.Settings {
+	return distributed.Settings{
+		Comparator: *comparatorName,
+		Blank:      *blankPolicy,
+		Normalize:  *normalize,
+		Transforms: bundler.SplitTransforms(*transform),
+	}
 }
 
-func main() {
-	start := time.Now()
-	defer func() {
-		elapsed := time.Since(start)
-		log.Infof("Finished after %s", elapsed)
-	}()
-	if *k < 2 {
-		log.Fatal("k cannot be less than 2")
+// newSortConfig creates config of sort pipeline from flags
+func newSortConfig() (sorter.Config, error) {
+	return newSortConfigFor(flagSettings())
+}
+
+// newSortConfigFor creates config of sort pipeline from settings of order and content of lines and rest of flags
+func newSortConfigFor(settings distributed.Settings) (cfg sorter.Config, err error) {
+	cmp, err := comparator.Get(settings.Comparator)
+	if err != nil {
 		return
 	}
 
-	if *n < 2 {
-		log.Fatal("n cannot be less than 2")
+	policy, err := blank.GetPolicy(settings.Blank)
+	if err != nil {
 		return
 	}
-	var inputSerializer inputserializer.InputSerializer
+	blankFilter := blank.NewFilter(policy)
+	cmp = blankFilter.Comparator(cmp)
+
+	recordPolicy, err := helper.GetRecordPolicy(*oversized)
+	if err != nil {
+		return
+	}
+
+	var sortTransform bundler.TransformFunc
+	switch *sortAlgorithm {
+	case "quick":
+		sortTransform = bundler.KeySortTransform(cmp)
+	case "radix":
+		if !cmp.IsBytewise() {
+			err = fmt.Errorf("radix sort is only available for bytewise comparator")
+			return
+		}
+		sortTransform = bundler.RadixSortTransform
+	default:
+		err = fmt.Errorf("unknown sort algorithm %s", *sortAlgorithm)
+		return
+	}
+
+	if *useArena && !cmp.IsBytewise() {
+		err = fmt.Errorf("arena is only available for bytewise comparator")
+		return
+	}
+
+	var transforms []bundler.TransformFunc
+	if settings.Normalize != "" {
+		if *useArena {
+			err = fmt.Errorf("arena cannot be used with normalization")
+			return
+		}
+		var form norm.Form
+		form, err = bundler.GetNormalizationForm(settings.Normalize)
+		if err != nil {
+			return
+		}
+		transforms = append(transforms, bundler.NormalizeTransform(form))
+	}
+	if len(settings.Transforms) > 0 {
+		if *useArena {
+			err = fmt.Errorf("arena cannot be used with transforms")
+			return
+		}
+		var lineTransforms []bundler.TransformFunc
+		lineTransforms, err = bundler.GetTransforms(settings.Transforms)
+		if err != nil {
+			return
+		}
+		transforms = append(transforms, lineTransforms...)
+	}
+
+	cfg = sorter.Config{
+		K:             *k,
+		N:             *n,
+		ChanBufSize:   *chanBuf,
+		IOBlockSize:   *ioBlockSize,
+		TempQuota:     *tempQuota << 20,
+		Arena:         *useArena,
+		Transforms:    transforms,
+		SortTransform: sortTransform,
+		Comparator:    cmp,
+		Count:         *count,
+		Blank:         blankFilter,
+		RecordLimit:   helper.RecordLimit{MaxSize: *maxRecord, Policy: recordPolicy},
+		SkipBinary:    *skipBinary,
+	}
+	if cfg.IOBlockSize == 0 && *ioMemory > 0 {
+		fitIOMemory(&cfg, *ioMemory<<20)
+	}
+	if cfg.ChanBufSize == 0 {
+		cfg.ChanBufSize = channelBufferSize(cfg.K, cfg.N)
+	}
+
+	return cfg, nil
+}
+
+// newDirSerializer creates serializer of input directory from flags
+func newDirSerializer() (*inputserializer.DirSerializer, error) {
+	since, err := parseTime(*modifiedSince)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modified-since time: %v", err)
+	}
+
+	dirSerializer := inputserializer.NewFilteredDirSerializer(*inputPath, inputserializer.Filter{
+		Include:        splitList(*include),
+		Exclude:        splitList(*exclude),
+		MaxDepth:       *maxDepth,
+		FollowSymlinks: *followSymlinks,
+		ModifiedSince:  since,
+	})
+	dirSerializer.SetReaders(*readers)
+
+	return dirSerializer, nil
+}
+
+// newSortSerializer creates serializer of input directory whose lines are read by cfg,
+// sort is stopped by cancel if input can't be read by the policy of oversized records
+func newSortSerializer(cfg sorter.Config, cancel context.CancelFunc) (*inputserializer.DirSerializer, error) {
+	dirSerializer, err := newDirSerializer()
+	if err != nil {
+		return nil, err
+	}
+	dirSerializer.SetBlankFilter(cfg.Blank)
+	dirSerializer.SetRecordLimit(cfg.RecordLimit)
+	dirSerializer.SetSkipBinary(cfg.SkipBinary)
+	dirSerializer.SetErrorHandler(func(error) { cancel() })
+
+	return dirSerializer, nil
+}
+
+// partitionedSort sorts input of dirSerializer by partitions, input is read twice, but keys are sampled
+// by a serializer of its own with its own blank lines filter, so only the sorting read is counted
+func partitionedSort(ctx context.Context, cancel context.CancelFunc, dirSerializer *inputserializer.DirSerializer,
+	tempPaths []string, outputPath string, cfg sorter.Config, pcfg sorter.PartitionConfig) error {
+	sampleCfg := cfg
+	sampleCfg.Blank = blank.NewFilter(cfg.Blank.Policy())
+	sampleSerializer, err := newSortSerializer(sampleCfg, cancel)
+	if err != nil {
+		return err
+	}
+	sampleSerializer.SetOrigins(*stable)
+	pcfg.SampleInput = sampleSerializer.GetSerializerCh
+
+	err = sorter.PartitionedSort(ctx, dirSerializer.GetSerializerCh, tempPaths, outputPath, cfg, pcfg)
+	if readErr := sampleSerializer.Err(); readErr != nil {
+		return fmt.Errorf("error in reading input to sample keys: %v", readErr)
+	}
+	return err
+}
+
+// getTempPaths returns temporary storage paths from flags, a new temporary directory is created if none is given
+func getTempPaths() ([]string, error) {
+	if *tempPath == "" {
+		dir, err := ioutil.TempDir("", "dir")
+		if err != nil {
+			return nil, fmt.Errorf("error in creating temporary directory: %v", err)
+		}
+		return []string{dir}, nil
+	}
+	return splitList(*tempPath), nil
+}
 
-	// Stop whole sub processes in case of exit
+// newSignalContext creates context which is cancelled on SIGINT and SIGTERM signals
+// to stop whole sub processes
+func newSignalContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
-	// handle SIGINT and SIGTERM signals
 	go func() {
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
@@ -85,75 +286,186 @@ func main() {
 		cancel()
 	}()
 
+	return ctx, cancel
+}
+
+// reportReadStats logs summary of what is not read of input as it is
+func reportReadStats(stats inputserializer.Stats) {
+	for _, skipped := range stats.SkippedFiles {
+		log.Infof("Skipped file %s: %s", skipped.Path, skipped.Reason)
+	}
+	if len(stats.SkippedFiles) > 0 {
+		log.Infof("Skipped %d files", len(stats.SkippedFiles))
+	}
+	if stats.TruncatedRecords > 0 || stats.SkippedRecords > 0 {
+		log.Infof("Truncated %d and skipped %d lines longer than %d bytes", stats.TruncatedRecords, stats.SkippedRecords, *maxRecord)
+	}
+}
+
+// runSort sorts lines of input directory files into output path
+func runSort() error {
+	start := time.Now()
+	cfg, err := newSortConfig()
+	if err != nil {
+		return err
+	}
+	if *reportPath != "" {
+		cfg.Stats = &sorter.Stats{}
+	}
+
+	ctx, cancel := newSignalContext()
+	defer cancel()
+
+	if monitor := startMemoryMonitor(); monitor != nil {
+		defer monitor.Stop()
+		cfg.Pressure = monitor.UnderPressure
+	}
+
 	log.Infof("Read input from directory: %s", *inputPath)
 	// Use File Serializer to read directory files' content
-	inputSerializer = inputserializer.NewDirSerializer(*inputPath)
-	readCh, err := inputSerializer.GetSerializerCh(ctx)
+	dirSerializer, err := newSortSerializer(cfg, cancel)
 	if err != nil {
-		log.Fatal(err)
-		return
+		return err
 	}
+	var inputSerializer inputserializer.InputSerializer = dirSerializer
 
-	if *tempPath == "" {
-		*tempPath, err = ioutil.TempDir("", "dir")
-		if err != nil {
-			log.Fatal("error in creating temporary directory:", err)
-			return
+	if *stable {
+		if *count || *incremental {
+			return fmt.Errorf("stable mode cannot be used with count aggregation or incremental sort")
+		}
+		if *sortAlgorithm != "quick" || *useArena {
+			return fmt.Errorf("stable mode is only available for quick sort without arena")
+		}
+		if *transform != "" {
+			// Transforms would change origins carried at the end of lines
+			return fmt.Errorf("stable mode cannot be used with transforms")
 		}
+		// Lines carry their origins through whole pipeline to break ties
+		cfg.Comparator = comparator.Stable(cfg.Comparator)
+		cfg.SortTransform = bundler.KeySortTransform(cfg.Comparator)
+		dirSerializer.SetOrigins(true)
 	}
 
-	b := bundler.GetNewBundler(*k)
-	b.AddTransformFunc(bundler.SortTransform)
+	tempPaths, err := getTempPaths()
+	if err != nil {
+		return err
+	}
 
-	bundlerCh := b.GetBundlerCh(ctx, readCh)
+	if !*skipPreflight {
+		inputSize, err := dirSerializer.InputSize()
+		if err != nil {
+			return err
+		}
+		err = preflight(inputSize, tempPaths, *outputPath, cfg.TempQuota)
+		if err != nil {
+			return fmt.Errorf("preflight check failed: %v", err)
+		}
+	}
 
-	chanBufSize := *k / *n
-	ts, err := tempstorage.NewTempStorage(*tempPath, chanBufSize)
+	if *incremental {
+		if *partitions > 1 {
+			return fmt.Errorf("incremental sort cannot be partitioned")
+		}
+		var readCh <-chan []string
+		readCh, err = inputSerializer.GetSerializerCh(ctx)
+		if err != nil {
+			return err
+		}
+		err = sorter.IncrementalSort(ctx, readCh, tempPaths, *outputPath, cfg)
+	} else if *partitions > 1 {
+		err = partitionedSort(ctx, cancel, dirSerializer, tempPaths, *outputPath, cfg,
+			sorter.PartitionConfig{Partitions: *partitions, SampleSize: *sampleSize})
+	} else {
+		var readCh <-chan []string
+		readCh, err = inputSerializer.GetSerializerCh(ctx)
+		if err != nil {
+			return err
+		}
+		err = sorter.Sort(ctx, readCh, tempPaths, *outputPath, cfg)
+	}
+	if readErr := dirSerializer.Err(); readErr != nil {
+		if !*incremental {
+			// Output of partial input is removed, incremental sort keeps the previous output
+			_ = os.Remove(*outputPath)
+		}
+		return fmt.Errorf("error in reading input: %v", readErr)
+	}
 	if err != nil {
-		log.Fatal("error in creating temporary storage", err)
-		return
+		return fmt.Errorf("error in sort: %v", err)
 	}
-	defer func() {
-		err = ts.Clean()
+	cfg.Blank.Report()
+	reportReadStats(dirSerializer.Stats())
+
+	if *reportPath != "" {
+		err = writeReport(newRunReport(dirSerializer.Stats(), cfg.Blank, cfg.Stats, start), *reportPath)
 		if err != nil {
-			log.Fatal("error in cleaning TempStorage:", err)
+			return fmt.Errorf("error in writing report: %v", err)
 		}
-	}()
+	}
+
+	return nil
+}
 
-	var wg sync.WaitGroup
-	var ch chan<- string
-	for bundle := range bundlerCh {
-		ch, err = ts.GetNextStoreCh(ctx, &wg)
+func main() {
+	err := loadSettings(flag.CommandLine, os.Args[1:], os.Environ())
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	setupLogging()
+
+	if *autoTuneFlag {
+		err = autoTune(flag.CommandLine)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("error in auto tune: %v", err)
 			return
 		}
+	}
 
-		wg.Add(1)
+	err = validateSettings()
+	if err != nil {
+		log.Fatalf("invalid settings: %v", err)
+		return
+	}
 
-		go func(ch chan<- string, bundle []string) {
-			for _, v := range bundle {
-				select {
-				case <-ctx.Done():
-					return
-				case ch <- v:
-				}
-			}
-			close(ch)
-		}(ch, bundle)
+	if *printConfig {
+		err = writeSettings(flag.CommandLine, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	wg.Wait()
+	runtime.GOMAXPROCS(*processorNumber)
+
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		log.Infof("Finished after %s", elapsed)
+	}()
 
-	var numberOfFileToMerge int
-	if *k < *n {
-		numberOfFileToMerge = *k
+	// Sort is run if no command is given
+	args := flag.Args()
+	if len(args) == 0 {
+		err = runSort()
 	} else {
-		numberOfFileToMerge = *n
+		switch args[0] {
+		case "worker":
+			err = runWorker(args[1:])
+		case "coordinator":
+			err = runCoordinator(args[1:])
+		case "merge":
+			err = runMerge(args[1:])
+		case "union", "intersect", "diff", "comm":
+			err = runSetOperation(args[0], args[1:])
+		default:
+			err = fmt.Errorf("unknown command %s", args[0])
+		}
 	}
-	err = merger.StartMerge(ctx, ts, *outputPath, numberOfFileToMerge)
+
 	if err != nil {
-		log.Fatal("error in merge:", err)
+		log.Fatal(err)
 		return
 	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"AID/solution/sysinfo"
+	"flag"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	memoryLimit    = flag.Int64("memory-limit", 0, "memory limit of the process in MB, 0 means the least of available memory and cgroup limit")
+	memoryPressure = flag.Int("memory-pressure", 80, "percent of memory limit from which runs are spilled early and I/O buffers are shrunk, 0 disables watching memory")
+)
+
+// memoryMonitorInterval is the period of reading memory stats
+const memoryMonitorInterval = 100 * time.Millisecond
+
+// getMemoryLimit returns memory limit in bytes from flags or system
+func getMemoryLimit() (uint64, error) {
+	if *memoryLimit > 0 {
+		return uint64(*memoryLimit) << 20, nil
+	}
+	return sysinfo.MemoryBudget()
+}
+
+// startMemoryMonitor starts watching memory by flags, it returns nil if watching is disabled or memory limit is unknown
+func startMemoryMonitor() *sysinfo.MemoryMonitor {
+	if *memoryPressure == 0 {
+		return nil
+	}
+
+	limit, err := getMemoryLimit()
+	if err != nil {
+		log.Warningf("Memory is not watched as its limit is unknown: %v", err)
+		return nil
+	}
+
+	log.Infof("Watch memory to not exceed %d%% of %d MB", *memoryPressure, limit>>20)
+	monitor := sysinfo.NewMemoryMonitor(limit, float64(*memoryPressure)/100, memoryMonitorInterval)
+	monitor.Start()
+	return monitor
+}
@@ -0,0 +1,66 @@
+package merger
+
+import (
+	"AID/solution/comparator"
+)
+
+// Cursor walks merged strings of sorted channels group by group,
+// each group is strings with equal keys
+type Cursor struct {
+	ms     mergeSource
+	cmp    *comparator.Comparator
+	counts []int
+}
+
+// NewCursor creates Cursor over read channels(chs) sorted by cmp
+func NewCursor(chs []<-chan []string, cmp *comparator.Comparator) *Cursor {
+	return &Cursor{
+		ms:     newMergeSource(chs, cmp),
+		cmp:    cmp,
+		counts: make([]int, len(chs)),
+	}
+}
+
+// Next moves to the next group of strings with equal keys
+// line is the first string of group, counts[i] is number of strings of group in chs[i]
+// counts is reused by next calls, ok is false if all channels are exhausted
+func (c *Cursor) Next() (line string, counts []int, ok bool, err error) {
+	if c.ms.Len() == 0 {
+		return "", nil, false, nil
+	}
+
+	for i := range c.counts {
+		c.counts[i] = 0
+	}
+
+	line, err = c.ms.getHead()
+	if err != nil {
+		return
+	}
+	key := c.cmp.Key(line)
+
+	for c.ms.Len() > 0 {
+		var head string
+		head, err = c.ms.getHead()
+		if err != nil {
+			return
+		}
+		if c.cmp.Key(head) != key {
+			break
+		}
+
+		var source int
+		source, err = c.ms.getHeadSource()
+		if err != nil {
+			return
+		}
+		c.counts[source]++
+
+		err = c.ms.updateHead()
+		if err != nil {
+			return
+		}
+	}
+
+	return line, c.counts, true, nil
+}
@@ -0,0 +1,41 @@
+package merger
+
+import (
+	"AID/solution/comparator"
+	"reflect"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	for _, size := range []int{2, loserTreeMinFanIn} {
+		sources := make([][]string, size)
+		sources[0] = []string{"a", "b", "b", "d"}
+		sources[1] = []string{"B", "c", "d"}
+
+		chs := sendAll(sources, 2)
+		cursor := NewCursor(chs, comparator.CaseFold)
+
+		var lines []string
+		var counts [][]int
+		for {
+			line, c, ok, err := cursor.Next()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !ok {
+				break
+			}
+			lines = append(lines, line)
+			counts = append(counts, append([]int(nil), c[:2]...))
+		}
+
+		if lines[0] != "a" || lines[2] != "c" || lines[3] != "d" || len(lines) != 4 {
+			t.Errorf("unexpected groups %v with %d sources", lines, size)
+		}
+		expected := [][]int{{1, 0}, {2, 1}, {0, 1}, {1, 1}}
+		if !reflect.DeepEqual(counts, expected) {
+			t.Errorf("counts are %v, expected %v with %d sources", counts, expected, size)
+		}
+	}
+}
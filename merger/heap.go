@@ -1,13 +1,58 @@
 package merger
 
 import (
+	"AID/solution/comparator"
 	"container/heap"
 	"fmt"
+	"strings"
 )
 
 type sourceItem struct {
-	ch    <-chan string // The channel to read the next value from
-	value string        // The head string of file which is processed
+	ch     <-chan []string        // The channel to read the next batch from
+	source int                    // Index of the channel in merged channels
+	batch  []string               // The current batch of file which is processed
+	pos    int                    // Position of value in batch
+	value  string                 // The head string of file which is processed
+	cmp    *comparator.Comparator // Comparator defines key of value
+	key    string                 // Normalized key of value
+	prefix uint64                 // First 8 bytes of key, compared before the whole key
+}
+
+// compare compares two source items like strings.Compare, by key prefix falling back to the whole key on ties,
+// equal keys are ordered by origins of values if comparator is stable
+func (item *sourceItem) compare(other *sourceItem) int {
+	if item.prefix != other.prefix {
+		if item.prefix < other.prefix {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(item.key, other.key); c != 0 {
+		return c
+	}
+	return item.cmp.CompareOrigin(item.value, other.value)
+}
+
+// less compares two source items
+func (item *sourceItem) less(other *sourceItem) bool {
+	return item.compare(other) < 0
+}
+
+// next moves sourceItem to the next string of its source
+// ok is false if source has no more string
+func (item *sourceItem) next() (ok bool) {
+	item.pos++
+	for item.pos >= len(item.batch) {
+		item.batch, ok = <-item.ch
+		if !ok {
+			return
+		}
+		item.pos = 0
+	}
+	item.value = item.batch[item.pos]
+	item.key = item.cmp.Key(item.value)
+	item.prefix = comparator.Prefix(item.key)
+	return true
 }
 
 // A sourceHeap implements heap.Interface and holds Items.
@@ -18,7 +63,7 @@ func (sh sourceHeap) Len() int { return len(sh) }
 
 // Less compare two source item
 func (sh sourceHeap) Less(i, j int) bool {
-	return sh[i].value < sh[j].value
+	return sh[i].less(sh[j])
 }
 
 // Swap swap two item in sourceHeap
@@ -52,6 +97,16 @@ func (sh *sourceHeap) getHead() (head string, err error) {
 	return
 }
 
+func (sh *sourceHeap) getHeadSource() (source int, err error) {
+	if sh.Len() < 1 {
+		err = fmt.Errorf("heap is empty")
+		return
+	}
+
+	source = (*sh)[0].source
+	return
+}
+
 // updateHead updates sourceItem at head and fixes the heap after modification
 func (sh *sourceHeap) updateHead() (err error) {
 	if sh.Len() < 1 {
@@ -59,9 +114,7 @@ func (sh *sourceHeap) updateHead() (err error) {
 		return
 	}
 	item := (*sh)[0]
-	newValue, ok := <-item.ch
-	if ok {
-		item.value = newValue
+	if item.next() {
 		heap.Fix(sh, 0)
 	} else {
 		heap.Pop(sh)
@@ -70,15 +123,18 @@ func (sh *sourceHeap) updateHead() (err error) {
 	return
 }
 
-// newSourceHeap creates and initializes Source Heap with read channels(chs)
-func newSourceHeap(chs []<-chan string) *sourceHeap {
+// newSourceHeap creates and initializes Source Heap with read channels(chs) ordered by cmp
+func newSourceHeap(chs []<-chan []string, cmp *comparator.Comparator) *sourceHeap {
 	sh := &sourceHeap{}
 	// Initial filling underneath slice without initializing heap
 	// to have O(k) complexity rather than O(k*log k) at inserting k elements
-	for _, ch := range chs {
-		item := &sourceItem{ch: ch, value: <-ch}
-		// Just append to the underneath slice and needles to initialize heap yet
-		sh.Push(item)
+	for i, ch := range chs {
+		item := &sourceItem{ch: ch, source: i, pos: -1, cmp: cmp}
+		// Empty sources are not added
+		if item.next() {
+			// Just append to the underneath slice and needles to initialize heap yet
+			sh.Push(item)
+		}
 	}
 
 	// Initialize heap
@@ -1,6 +1,8 @@
 package merger
 
 import (
+	"AID/solution/comparator"
+	"AID/solution/record"
 	"fmt"
 	"testing"
 )
@@ -11,18 +13,18 @@ func padNumberWithZero(value int) string {
 func TestSourceHeap(t *testing.T) {
 	numberOfChannels := 4000
 
-	chs := make([]<-chan string, 0, numberOfChannels)
+	chs := make([]<-chan []string, 0, numberOfChannels)
 
 	for i := 0; i < numberOfChannels; i++ {
-		ch := make(chan string)
+		ch := make(chan []string)
 		chs = append(chs, ch)
 		go func(i int) {
-			ch <- "Hello " + padNumberWithZero(i)
+			ch <- []string{"Hello " + padNumberWithZero(i)}
 			close(ch)
 		}(i)
 	}
 
-	sh := newSourceHeap(chs)
+	sh := newSourceHeap(chs, comparator.Bytewise)
 
 	if sh.Len() != numberOfChannels {
 		t.Errorf("heap length is %d, but should be %d", sh.Len(), numberOfChannels)
@@ -51,18 +53,18 @@ func TestSourceHeap(t *testing.T) {
 func TestSourceHeap_PutReverse(t *testing.T) {
 	numberOfChannels := 40001
 
-	chs := make([]<-chan string, 0, numberOfChannels)
+	chs := make([]<-chan []string, 0, numberOfChannels)
 
 	for i := 0; i < numberOfChannels; i++ {
-		ch := make(chan string)
+		ch := make(chan []string)
 		chs = append(chs, ch)
 		go func(i int) {
-			ch <- "Hello " + padNumberWithZero(numberOfChannels-i-1)
+			ch <- []string{"Hello " + padNumberWithZero(numberOfChannels-i-1)}
 			close(ch)
 		}(i)
 	}
 
-	sh := newSourceHeap(chs)
+	sh := newSourceHeap(chs, comparator.Bytewise)
 
 	if sh.Len() != numberOfChannels {
 		t.Errorf("heap length is %d, but should be %d", sh.Len(), numberOfChannels)
@@ -92,22 +94,32 @@ func TestSourceHeap_UpdateHead(t *testing.T) {
 	numberOfChannels := 40
 	numberOfStringPerChannel := 30
 
-	chs := make([]<-chan string, 0, numberOfChannels)
+	chs := make([]<-chan []string, 0, numberOfChannels)
 
 	for i := 0; i < numberOfChannels; i++ {
-		ch := make(chan string)
+		ch := make(chan []string)
 		chs = append(chs, ch)
 		go func(i int) {
+			// Send in batches of different sizes, including empty ones
+			var batch []string
 			for j := 0; j < numberOfStringPerChannel; j++ {
-				ch <- "Hello" +
-					" " + padNumberWithZero(numberOfChannels-i-1) +
-					" " + padNumberWithZero(j)
+				batch = append(batch, "Hello"+
+					" "+padNumberWithZero(numberOfChannels-i-1)+
+					" "+padNumberWithZero(j))
+				if j%(i%4+1) == 0 {
+					ch <- batch
+					ch <- nil
+					batch = nil
+				}
+			}
+			if len(batch) > 0 {
+				ch <- batch
 			}
 			close(ch)
 		}(i)
 	}
 
-	sh := newSourceHeap(chs)
+	sh := newSourceHeap(chs, comparator.Bytewise)
 
 	if sh.Len() != numberOfChannels {
 		t.Errorf("heap length is %d, but should be %d", sh.Len(), numberOfChannels)
@@ -136,3 +148,109 @@ func TestSourceHeap_UpdateHead(t *testing.T) {
 	}
 
 }
+
+func TestSourceHeap_EmptySource(t *testing.T) {
+	empty := make(chan []string)
+	close(empty)
+	full := make(chan []string, 1)
+	full <- []string{"Hello"}
+	close(full)
+
+	sh := newSourceHeap([]<-chan []string{empty, full}, comparator.Bytewise)
+	if sh.Len() != 1 {
+		t.Errorf("heap length is %d, but should be 1", sh.Len())
+	}
+}
+
+func benchmarkSourceHeap(b *testing.B, batchSize int) {
+	numberOfChannels := 100
+	numberOfStringPerChannel := 10000
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		chs := make([]<-chan []string, 0, numberOfChannels)
+		for c := 0; c < numberOfChannels; c++ {
+			ch := make(chan []string)
+			chs = append(chs, ch)
+			go func(c int) {
+				batch := make([]string, 0, batchSize)
+				for j := 0; j < numberOfStringPerChannel; j++ {
+					batch = append(batch, padNumberWithZero(j)+padNumberWithZero(c))
+					if len(batch) == batchSize {
+						ch <- batch
+						batch = make([]string, 0, batchSize)
+					}
+				}
+				close(ch)
+			}(c)
+		}
+		b.StartTimer()
+
+		sh := newSourceHeap(chs, comparator.Bytewise)
+		for sh.Len() > 0 {
+			_ = sh.updateHead()
+		}
+	}
+}
+
+// BenchmarkSourceHeap_SingleLine moves one line per channel operation
+func BenchmarkSourceHeap_SingleLine(b *testing.B) {
+	benchmarkSourceHeap(b, 1)
+}
+
+// BenchmarkSourceHeap_Batch moves 1000 lines per channel operation
+func BenchmarkSourceHeap_Batch(b *testing.B) {
+	benchmarkSourceHeap(b, 1000)
+}
+
+func TestSourceHeap_CaseFold(t *testing.T) {
+	sources := [][]string{
+		{"apple", "Beer", "king Ludwig 00001"},
+		{"Apple", "bratwurst", "King ludwig 00000", "king ludwig 00002"},
+		{"BEER", "Currywurst"},
+	}
+
+	for _, ms := range []mergeSource{
+		newSourceHeap(sendAll(sources, 2), comparator.CaseFold),
+		newLoserTree(sendAll(sources, 2), comparator.CaseFold),
+	} {
+		result := drain(t, ms)
+		if len(result) != 9 {
+			t.Errorf("merged %d strings, but should be 9", len(result))
+			continue
+		}
+		for i := 1; i < len(result); i++ {
+			if comparator.CaseFold.Less(result[i], result[i-1]) {
+				t.Errorf("%v is not sorted ignoring case", result)
+				break
+			}
+		}
+	}
+}
+
+func TestSourceItem_Compare(t *testing.T) {
+	stable := comparator.Stable(comparator.Bytewise)
+	newItem := func(cmp *comparator.Comparator, value string) *sourceItem {
+		key := cmp.Key(value)
+		return &sourceItem{value: value, cmp: cmp, key: key, prefix: comparator.Prefix(key)}
+	}
+
+	tests := []struct {
+		cmp      *comparator.Comparator
+		a, b     string
+		expected int
+	}{
+		{comparator.Bytewise, "apple", "beer", -1},
+		{comparator.Bytewise, "beer", "apple", 1},
+		{comparator.Bytewise, "bratwurst1", "bratwurst0", 1}, // equal prefixes
+		{comparator.Bytewise, "beer", "beer", 0},
+		{stable, record.Tag("beer", 0, 1), record.Tag("beer", 0, 0), 1},
+		{stable, record.Tag("beer", 1, 0), record.Tag("beer", 1, 0), 0},
+	}
+	for _, test := range tests {
+		c := newItem(test.cmp, test.a).compare(newItem(test.cmp, test.b))
+		if c != test.expected {
+			t.Errorf("compare of %q and %q is %d, but should be %d", test.a, test.b, c, test.expected)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package merger
+
+import (
+	"AID/solution/comparator"
+	"fmt"
+)
+
+// loserTreeMinFanIn is the fan-in from which loserTree is used rather than sourceHeap
+const loserTreeMinFanIn = 64
+
+// mergeSource gives the smallest head string of multiple sorted sources
+type mergeSource interface {
+	Len() int
+	getHead() (head string, err error)
+	getHeadSource() (source int, err error)
+	updateHead() (err error)
+}
+
+// newMergeSource creates mergeSource for read channels(chs) ordered by cmp,
+// the implementation is chosen by fan-in
+func newMergeSource(chs []<-chan []string, cmp *comparator.Comparator) mergeSource {
+	if len(chs) >= loserTreeMinFanIn {
+		return newLoserTree(chs, cmp)
+	}
+	return newSourceHeap(chs, cmp)
+}
+
+// A loserTree is a tournament tree which keeps loser of each match in internal nodes,
+// replacing the winner needs log k comparisons as only its path to root is replayed
+type loserTree struct {
+	items     []*sourceItem
+	exhausted []bool // exhausted sources are greater than any string
+	tree      []int  // tree[0] is index of the winner, tree[1:] are indexes of losers
+	active    int    // number of not exhausted sources
+}
+
+// less compares two sources, ties are broken by index to have a strict order
+func (lt *loserTree) less(i, j int) bool {
+	if lt.exhausted[i] || lt.exhausted[j] {
+		return !lt.exhausted[i] && lt.exhausted[j]
+	}
+	if c := lt.items[i].compare(lt.items[j]); c != 0 {
+		return c < 0
+	}
+	return i < j
+}
+
+// build plays matches of subtree under node and returns its winner
+// leaves are nodes k to 2k-1
+func (lt *loserTree) build(node int) int {
+	k := len(lt.items)
+	if node >= k {
+		return node - k
+	}
+
+	left, right := lt.build(2*node), lt.build(2*node+1)
+	if lt.less(left, right) {
+		lt.tree[node] = right
+		return left
+	}
+	lt.tree[node] = left
+	return right
+}
+
+// Len number of not exhausted sources
+func (lt *loserTree) Len() int { return lt.active }
+
+func (lt *loserTree) getHead() (head string, err error) {
+	if lt.active < 1 {
+		err = fmt.Errorf("loser tree is empty")
+		return
+	}
+
+	head = lt.items[lt.tree[0]].value
+	return
+}
+
+func (lt *loserTree) getHeadSource() (source int, err error) {
+	if lt.active < 1 {
+		err = fmt.Errorf("loser tree is empty")
+		return
+	}
+
+	source = lt.tree[0]
+	return
+}
+
+// updateHead moves winner source to its next string and replays its path to root
+func (lt *loserTree) updateHead() (err error) {
+	if lt.active < 1 {
+		err = fmt.Errorf("loser tree is empty")
+		return
+	}
+
+	winner := lt.tree[0]
+	if !lt.items[winner].next() {
+		lt.exhausted[winner] = true
+		lt.active--
+	}
+
+	for node := (winner + len(lt.items)) / 2; node > 0; node /= 2 {
+		if lt.less(lt.tree[node], winner) {
+			lt.tree[node], winner = winner, lt.tree[node]
+		}
+	}
+	lt.tree[0] = winner
+
+	return
+}
+
+// newLoserTree creates and initializes loser tree with read channels(chs) ordered by cmp
+func newLoserTree(chs []<-chan []string, cmp *comparator.Comparator) *loserTree {
+	lt := &loserTree{
+		items:     make([]*sourceItem, len(chs)),
+		exhausted: make([]bool, len(chs)),
+		tree:      make([]int, len(chs)),
+	}
+	if len(chs) == 0 {
+		return lt
+	}
+
+	for i, ch := range chs {
+		lt.items[i] = &sourceItem{ch: ch, source: i, pos: -1, cmp: cmp}
+		if lt.items[i].next() {
+			lt.active++
+		} else {
+			lt.exhausted[i] = true
+		}
+	}
+
+	lt.tree[0] = lt.build(1)
+
+	return lt
+}
@@ -0,0 +1,125 @@
+package merger
+
+import (
+	"AID/solution/comparator"
+	"sort"
+	"testing"
+)
+
+// sendAll creates channels which send given sorted sources in batches of batchSize
+func sendAll(sources [][]string, batchSize int) []<-chan []string {
+	chs := make([]<-chan []string, 0, len(sources))
+	for _, source := range sources {
+		ch := make(chan []string)
+		chs = append(chs, ch)
+		go func(source []string) {
+			for i := 0; i < len(source); i += batchSize {
+				end := i + batchSize
+				if end > len(source) {
+					end = len(source)
+				}
+				ch <- source[i:end]
+			}
+			close(ch)
+		}(source)
+	}
+	return chs
+}
+
+func drain(t testing.TB, ms mergeSource) []string {
+	var result []string
+	for ms.Len() > 0 {
+		head, err := ms.getHead()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, head)
+
+		err = ms.updateHead()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return result
+}
+
+func TestLoserTree(t *testing.T) {
+	for _, numberOfChannels := range []int{0, 1, 2, 3, 7, 64, 100} {
+		var sources [][]string
+		var expected []string
+		for i := 0; i < numberOfChannels; i++ {
+			// Sources of different length, some of them empty
+			var source []string
+			for j := 0; j < (i*7)%11; j++ {
+				source = append(source, padNumberWithZero((j*31+i)%50))
+			}
+			sort.Strings(source)
+			sources = append(sources, source)
+			expected = append(expected, source...)
+		}
+		sort.Strings(expected)
+
+		lt := newLoserTree(sendAll(sources, 3), comparator.Bytewise)
+		result := drain(t, lt)
+
+		if len(result) != len(expected) {
+			t.Errorf("%d channels: merged %d strings, but should be %d", numberOfChannels, len(result), len(expected))
+			continue
+		}
+		for i := range result {
+			if result[i] != expected[i] {
+				t.Errorf("%d channels: string %d is \"%s\", but should be \"%s\"", numberOfChannels, i, result[i], expected[i])
+				break
+			}
+		}
+
+		if _, err := lt.getHead(); err == nil {
+			t.Error("getHead of empty loser tree should return error")
+		}
+	}
+}
+
+func TestNewMergeSource(t *testing.T) {
+	if _, ok := newMergeSource(sendAll(make([][]string, loserTreeMinFanIn-1), 1), comparator.Bytewise).(*sourceHeap); !ok {
+		t.Errorf("heap should be used for fan-in less than %d", loserTreeMinFanIn)
+	}
+	if _, ok := newMergeSource(sendAll(make([][]string, loserTreeMinFanIn), 1), comparator.Bytewise).(*loserTree); !ok {
+		t.Errorf("loser tree should be used for fan-in of %d", loserTreeMinFanIn)
+	}
+}
+
+func benchmarkMerge1000Runs(b *testing.B, newSource func(chs []<-chan []string) mergeSource) {
+	numberOfRuns := 1000
+	numberOfStringPerRun := 1000
+
+	sources := make([][]string, numberOfRuns)
+	for i := range sources {
+		for j := 0; j < numberOfStringPerRun; j++ {
+			sources[i] = append(sources[i], padNumberWithZero(j)+padNumberWithZero(i))
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		chs := sendAll(sources, numberOfStringPerRun)
+		b.StartTimer()
+
+		ms := newSource(chs)
+		for ms.Len() > 0 {
+			_ = ms.updateHead()
+		}
+	}
+}
+
+func BenchmarkSourceHeap_Merge1000Runs(b *testing.B) {
+	benchmarkMerge1000Runs(b, func(chs []<-chan []string) mergeSource {
+		return newSourceHeap(chs, comparator.Bytewise)
+	})
+}
+
+func BenchmarkLoserTree_Merge1000Runs(b *testing.B) {
+	benchmarkMerge1000Runs(b, func(chs []<-chan []string) mergeSource {
+		return newLoserTree(chs, comparator.Bytewise)
+	})
+}
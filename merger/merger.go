@@ -1,15 +1,29 @@
 package merger
 
 import (
+	"AID/solution/comparator"
+	"AID/solution/helper"
 	"AID/solution/tempstorage"
+	"bufio"
 	"context"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"sync"
 )
 
-// StartMerge run merge process
-func StartMerge(ctx context.Context, ts *tempstorage.TempStorage, outputPath string, numberOfFileToMerge int) error {
+// StartMerge run merge process, stored files should be sorted by cmp
+func StartMerge(ctx context.Context, ts *tempstorage.TempStorage, outputPath string, numberOfFileToMerge int, cmp *comparator.Comparator) error {
+
+	if ts.StoreFileCount() == 0 {
+		// No run is stored, e.g. all lines are dropped, so output is empty
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}
 
 	for {
 		if hasSingle, resultPath := ts.HasSingleStoredFile(); hasSingle {
@@ -27,75 +41,229 @@ func StartMerge(ctx context.Context, ts *tempstorage.TempStorage, outputPath str
 			return err
 		}
 
-		var wg sync.WaitGroup
-		var rChs []<-chan string
-		goNextLevel := false
+		err = mergeLevel(ctx, ts, numberOfFileToMerge, cmp)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			log.Error("merge stopped before completion")
+			return nil
+		}
+	}
+	return nil
+}
+
+// MergeLevels merges stored files level by level till they can be merged at once,
+// then returns read channels of the remaining files rather than merging them,
+// so the caller can process the final merge, e.g. by Merge
+// Stored files should be sorted by cmp
+func MergeLevels(ctx context.Context, ts *tempstorage.TempStorage, numberOfFileToMerge int, cmp *comparator.Comparator) ([]<-chan []string, error) {
+	if err := checkFanIn(numberOfFileToMerge); err != nil {
+		return nil, err
+	}
+	for {
+		err := ts.SetupNextLevel()
+		if err != nil {
+			log.Errorf("error on setting up next level of TempStorage: %v", err)
+			return nil, err
+		}
+
+		if ts.ReadFileCount() <= numberOfFileToMerge {
+			return ts.GetNextReadChs(ctx, numberOfFileToMerge)
+		}
+
+		err = mergeLevel(ctx, ts, numberOfFileToMerge, cmp)
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// checkFanIn checks whether merging groups of numberOfFileToMerge files reduces number of files,
+// otherwise levels would be merged forever
+func checkFanIn(numberOfFileToMerge int) error {
+	if numberOfFileToMerge < 2 {
+		return fmt.Errorf("at least 2 files should be merged at once, not %d", numberOfFileToMerge)
+	}
+	return nil
+}
+
+// mergeLevel merges files of read level in groups of numberOfFileToMerge into files of store level
+// It returns nil if ctx is done before completion
+func mergeLevel(ctx context.Context, ts *tempstorage.TempStorage, numberOfFileToMerge int, cmp *comparator.Comparator) error {
+	if err := checkFanIn(numberOfFileToMerge); err != nil {
+		return err
+	}
+	// Processes of a failed group are stopped by cancel to not leave them blocked
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		default:
+		}
+
+		// read channels
+		rChs, err := ts.GetNextReadChs(ctx, numberOfFileToMerge)
+		if err != nil {
+			log.Errorf("error on getting next read channels of TempStorage: %v", err)
+			abort(cancel, nil, &wg)
+			return err
+		}
+
+		// all read files have been processed, should go to next level
+		if len(rChs) == 0 {
+			break
+		}
+
+		// store channel
+		wg.Add(1)
+		sCh, err := ts.GetNextStoreCh(ctx, &wg)
+		if err != nil {
+			wg.Done()
+			log.Errorf("error on getting next store channel of TempStorage: %v", err)
+			abort(cancel, rChs, &wg)
+			return err
+		}
+
+		sh := newMergeSource(rChs, cmp)
+		batch := make([]string, 0, helper.BatchSize)
 
-		for {
+		for sh.Len() > 0 {
 			select {
 			case <-ctx.Done():
-				log.Error("merge stopped before completion")
+				log.Warning("Merger stopped before finishing its job")
+				close(sCh)
+				wg.Wait()
 				return nil
 			default:
-				// read channels
-				rChs, err = ts.GetNextReadChs(ctx, numberOfFileToMerge)
+				var head string
+				head, err = sh.getHead()
 				if err != nil {
-					log.Errorf("error on getting next read channels of TempStorage: %v", err)
+					log.Errorf("error on getting smallest string from min heap: %v", err)
+					close(sCh)
+					abort(cancel, rChs, &wg)
 					return err
 				}
 
-				// all read files have been processed, should go to next level
-				if len(rChs) == 0 {
-					goNextLevel = true
-					break
+				// Write to store channel
+				batch = append(batch, head)
+				if len(batch) == helper.BatchSize {
+					if !send(ctx, sCh, batch) {
+						log.Warning("Merger stopped before finishing its job")
+						close(sCh)
+						wg.Wait()
+						return nil
+					}
+					batch = make([]string, 0, helper.BatchSize)
 				}
 
-				// store channel
-				var sCh chan<- string
-				sCh, err = ts.GetNextStoreCh(ctx, &wg)
+				// Update source item and heap
+				err = sh.updateHead()
 				if err != nil {
-					log.Errorf("error on getting next store channel of TempStorage: %v", err)
+					log.Errorf("error on updating head of min heap: %v", err)
+					close(sCh)
+					abort(cancel, rChs, &wg)
 					return err
 				}
-				wg.Add(1)
+			}
+		}
 
-				sh := newSourceHeap(rChs)
+		if len(batch) > 0 && !send(ctx, sCh, batch) {
+			log.Warning("Merger stopped before finishing its job")
+			close(sCh)
+			wg.Wait()
+			return nil
+		}
+		close(sCh)
+	}
 
-				for sh.Len() > 0 {
-					select {
-					case <-ctx.Done():
-						log.Warning("Merger stopped before finishing its job")
-						return nil
-					default:
-						var head string
-						head, err = sh.getHead()
-						if err != nil {
-							log.Errorf("error on getting smallest string from min heap: %v", err)
-							return err
-						}
-
-						// Write to store channel
-						sCh <- head
-
-						// Update source item and heap
-						err = sh.updateHead()
-						if err != nil {
-							log.Errorf("error on updating head of min heap: %v", err)
-							return err
-						}
-					}
-				}
+	// Wait till all store processes become complete
+	wg.Wait()
 
-				close(sCh)
-			}
+	err := ts.Err()
+	if err != nil {
+		log.Errorf("error on storing merged files: %v", err)
+		return err
+	}
+	return nil
+}
 
-			if goNextLevel {
-				break
-			}
+// send puts batch in store channel ch unless ctx is done first, returns whether batch is sent
+// Store process stops on ctx done, so an unselected send would block forever
+func send(ctx context.Context, ch chan<- []string, batch []string) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- batch:
+		return true
+	}
+}
+
+// abort stops processes of the current merge group by cancel, drains read channels rChs
+// and waits for store processes, so none of them is left blocked
+func abort(cancel context.CancelFunc, rChs []<-chan []string, wg *sync.WaitGroup) {
+	cancel()
+	for _, ch := range rChs {
+		for range ch {
 		}
+	}
+	wg.Wait()
+}
+
+// Merge merges sorted read channels(chs) into w, one line per each string
+// Strings of chs should be sorted by cmp
+func Merge(ctx context.Context, chs []<-chan []string, w io.Writer, cmp *comparator.Comparator) error {
+	writer := bufio.NewWriter(w)
 
-		// Wait till all store processes become complete
-		wg.Wait()
+	err := MergeFunc(ctx, chs, cmp, func(line string) error {
+		_, err := writer.WriteString(line)
+		if err == nil {
+			err = writer.WriteByte('\n')
+		}
+		return err
+	})
+	if err != nil {
+		return err
 	}
+
+	return writer.Flush()
+}
+
+// MergeFunc merges sorted read channels(chs) and calls fn for each string in order
+// Strings of chs should be sorted by cmp
+func MergeFunc(ctx context.Context, chs []<-chan []string, cmp *comparator.Comparator, fn func(line string) error) error {
+	ms := newMergeSource(chs, cmp)
+
+	for ms.Len() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		head, err := ms.getHead()
+		if err != nil {
+			return err
+		}
+
+		err = fn(head)
+		if err != nil {
+			return err
+		}
+
+		err = ms.updateHead()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -1,15 +1,20 @@
 package merger
 
 import (
+	"AID/solution/comparator"
 	"AID/solution/helper"
 	"AID/solution/tempstorage"
 	"bufio"
+	"bytes"
 	"context"
 	"io"
 	"os"
+	"path"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestStartMerge(t *testing.T) {
@@ -53,7 +58,7 @@ func TestStartMerge(t *testing.T) {
 
 	k := 4 // Bundle size
 
-	var ch chan<- string
+	var ch chan<- []string
 	bundle := make([]string, 0, k)
 	var wg sync.WaitGroup
 	for i := 0; i < len(sampleData); i++ {
@@ -67,11 +72,9 @@ func TestStartMerge(t *testing.T) {
 			wg.Add(1)
 
 			sort.Strings(bundle)
-			for _, v := range bundle {
-				ch <- v
-			}
+			ch <- bundle
 			close(ch)
-			bundle = bundle[:0] // Make bundle clear
+			bundle = make([]string, 0, k) // Sent bundle is owned by store channel
 		}
 	}
 
@@ -79,7 +82,7 @@ func TestStartMerge(t *testing.T) {
 
 	outputPath := "testData/out.txt"
 
-	err = StartMerge(ctx, ts, outputPath, k)
+	err = StartMerge(ctx, ts, outputPath, k, comparator.Bytewise)
 
 	hasSingle, _ := ts.HasSingleStoredFile()
 	if !hasSingle {
@@ -131,3 +134,96 @@ func TestStartMerge(t *testing.T) {
 	}
 
 }
+
+func TestMerge(t *testing.T) {
+	sources := [][]string{
+		{"aaa", "ccc", "eee"},
+		{},
+		{"bbb", "ccc", "fff", "ggg"},
+		{"ddd"},
+	}
+
+	var buf bytes.Buffer
+	err := Merge(context.Background(), sendAll(sources, 2), &buf, comparator.Bytewise)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "aaa\nbbb\nccc\nccc\nddd\neee\nfff\nggg\n"
+	if buf.String() != expected {
+		t.Errorf("merged output is %q, but should be %q", buf.String(), expected)
+	}
+}
+
+func TestMergeLevels_FanIn(t *testing.T) {
+	root := path.Join("testData", "fanIn")
+	if err := helper.MakeCleanDir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	ts, err := tempstorage.NewTempStorage(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = ts.StoreNextFile(strings.NewReader("line\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Merging one file at once never reduces number of files
+	_, err = MergeLevels(context.Background(), ts, 1, comparator.Bytewise)
+	if err == nil {
+		t.Error("merge levels by one file at once should fail")
+	}
+	err = StartMerge(context.Background(), ts, path.Join(root, "out.txt"), 1, comparator.Bytewise)
+	if err == nil {
+		t.Error("start merge by one file at once should fail")
+	}
+}
+
+func TestStartMerge_Cancel(t *testing.T) {
+	root := path.Join("testData", "cancel")
+	if err := helper.MakeCleanDir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	ts, err := tempstorage.NewTempStorage(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := strings.Repeat("line\n", 20*helper.BatchSize)
+	for i := 0; i < 8; i++ {
+		if err = ts.StoreNextFile(strings.NewReader(run)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Store processes stop on cancel, merger shouldn't be blocked on sending to them
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- StartMerge(ctx, ts, path.Join(root, "out.txt"), 2, comparator.Bytewise)
+	}()
+	time.Sleep(time.Millisecond)
+	cancel()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("merge isn't stopped after cancel")
+	}
+	if err = ts.Clean(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"AID/solution/helper"
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// preflight checks whether temporary and output filesystems have enough free space
+// and temporary quota is enough to sort input of inputSize bytes
+// Input is stored uncompressed, so each level of temporary storage needs up to inputSize bytes
+func preflight(inputSize int64, tempPaths []string, outputPath string, tempQuota int64) error {
+	if tempQuota > 0 && inputSize > tempQuota {
+		return fmt.Errorf("input size is %d bytes, but temporary storage quota is %d bytes", inputSize, tempQuota)
+	}
+
+	// Temporary files are striped across temporary paths
+	needed := inputSize / int64(len(tempPaths))
+	for _, tempPath := range tempPaths {
+		free, err := helper.FreeSpace(tempPath)
+		if err != nil {
+			log.Warningf("Unable to check free space of %s: %v", tempPath, err)
+			continue
+		}
+		if uint64(needed) > free {
+			return fmt.Errorf("temporary path %s has %d bytes free, but %d bytes are needed", tempPath, free, needed)
+		}
+		log.Infof("Temporary path %s has %d bytes free, %d bytes are needed", tempPath, free, needed)
+	}
+
+	// Result is moved from the first temporary path, it needs space only on a different filesystem
+	outputDir := filepath.Dir(outputPath)
+	same, err := helper.SameFilesystem(outputDir, tempPaths[0])
+	if err != nil {
+		log.Warningf("Unable to check filesystem of %s: %v", outputDir, err)
+		return nil
+	}
+	if same {
+		return nil
+	}
+
+	free, err := helper.FreeSpace(outputDir)
+	if err != nil {
+		log.Warningf("Unable to check free space of %s: %v", outputDir, err)
+		return nil
+	}
+	if uint64(inputSize) > free {
+		return fmt.Errorf("output path %s has %d bytes free, but %d bytes are needed", outputDir, free, inputSize)
+	}
+	log.Infof("Output path %s has %d bytes free, %d bytes are needed", outputDir, free, inputSize)
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package record
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// OriginSize is the size of origin suffix of tagged lines,
+// origin is file index and line number in big-endian hex, so origins are ordered byte-wise
+const OriginSize = 2 * (4 + 8)
+
+// Tag appends origin of line, which is index of its file and its number in the file
+func Tag(line string, fileIndex uint32, lineNumber uint64) string {
+	var origin [OriginSize / 2]byte
+	binary.BigEndian.PutUint32(origin[:4], fileIndex)
+	binary.BigEndian.PutUint64(origin[4:], lineNumber)
+	return line + hex.EncodeToString(origin[:])
+}
+
+// Line returns line of tagged line without its origin
+func Line(tagged string) string {
+	if len(tagged) < OriginSize {
+		return tagged
+	}
+	return tagged[:len(tagged)-OriginSize]
+}
+
+// Origin returns origin suffix of tagged line
+func Origin(tagged string) string {
+	if len(tagged) < OriginSize {
+		return ""
+	}
+	return tagged[len(tagged)-OriginSize:]
+}
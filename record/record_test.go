@@ -0,0 +1,23 @@
+package record
+
+import (
+	"testing"
+)
+
+func TestTag(t *testing.T) {
+	tagged := Tag("some line", 3, 42)
+	if Line(tagged) != "some line" {
+		t.Errorf("line of tagged line is %q", Line(tagged))
+	}
+	if len(Origin(tagged)) != OriginSize {
+		t.Errorf("origin size is %d", len(Origin(tagged)))
+	}
+
+	// Origins are ordered by file index then line number
+	ordered := []string{Tag("", 0, 300), Tag("", 1, 2), Tag("", 1, 10), Tag("", 256, 0)}
+	for i := 1; i < len(ordered); i++ {
+		if Origin(ordered[i-1]) >= Origin(ordered[i]) {
+			t.Errorf("origin %s should be less than %s", Origin(ordered[i-1]), Origin(ordered[i]))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"AID/solution/blank"
+	"AID/solution/inputserializer"
+	"AID/solution/sorter"
+	"AID/solution/sysinfo"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"time"
+)
+
+var reportPath = flag.String("report", "", "path of JSON summary of sort written at the end, - means standard output")
+
+// blankReport summarizes blank lines by their policy
+type blankReport struct {
+	Policy string `json:"policy"`
+	Blank  int64  `json:"blank"`  // dropped or counted blank lines
+	Padded int64  `json:"padded"` // counted lines with leading or trailing white space
+}
+
+// runReport is the summary of a sort written as JSON, it is used by orchestration to alert on anomalies
+type runReport struct {
+	Input         inputserializer.Stats `json:"input"`
+	BlankLines    blankReport           `json:"blank_lines"`
+	*sorter.Stats                       // runs, merge levels, stages and output
+	PeakMemory    uint64                `json:"peak_memory_bytes"`
+	Seconds       float64               `json:"seconds"`
+}
+
+// newRunReport creates summary of a sort started at start
+func newRunReport(input inputserializer.Stats, filter *blank.Filter, stats *sorter.Stats, start time.Time) runReport {
+	if input.SkippedFiles == nil {
+		// Empty list rather than null is easier to consume
+		input.SkippedFiles = []inputserializer.SkippedFile{}
+	}
+	return runReport{
+		Input: input,
+		BlankLines: blankReport{
+			Policy: filter.Policy().String(),
+			Blank:  filter.Blank(),
+			Padded: filter.Padded(),
+		},
+		Stats:      stats,
+		PeakMemory: sysinfo.PeakMemory(),
+		Seconds:    time.Since(start).Seconds(),
+	}
+}
+
+// writeReport writes report as JSON to file located at path, or to standard output if path is -
+func writeReport(report runReport, path string) (err error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		var file *os.File
+		file, err = os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
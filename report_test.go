@@ -0,0 +1,147 @@
+package main
+
+import (
+	"AID/solution/blank"
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"AID/solution/inputserializer"
+	"AID/solution/sorter"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	filter := blank.NewFilter(blank.Drop)
+	report := newRunReport(inputserializer.Stats{Files: 2}, filter, &sorter.Stats{Runs: 3}, time.Now())
+	path := filepath.Join(dir, "report.json")
+	err = writeReport(report, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(content, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"input", "blank_lines", "runs", "levels", "stages", "output", "peak_memory_bytes", "seconds"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("report has no %s field", key)
+		}
+	}
+	if string(fields["runs"]) != "3" {
+		t.Errorf("runs is %s, but should be 3", fields["runs"])
+	}
+
+	var input struct {
+		Files        int               `json:"files"`
+		SkippedFiles []json.RawMessage `json:"skipped_files"`
+	}
+	err = json.Unmarshal(fields["input"], &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.Files != 2 || input.SkippedFiles == nil {
+		t.Errorf("input is %s, but should have 2 files and empty skipped files", fields["input"])
+	}
+}
+
+// sortPartitioned sorts 2 input files of 9 lines, 3 of them blank, by 4 partitions
+func sortPartitioned(t *testing.T, policy blank.Policy) (*inputserializer.DirSerializer, sorter.Config) {
+	dir, err := ioutil.TempDir("", "partitioned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	input := filepath.Join(dir, "input")
+	temp := filepath.Join(dir, "temp")
+	for _, d := range []string{input, temp} {
+		if err = os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"a.txt": "pear\n\napple\n \nfig\n",
+		"b.txt": "kiwi\n\nplum\nlime\n",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(input, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(path string) {
+		*inputPath = path
+	}(*inputPath)
+	*inputPath = input
+
+	cfg := sorter.Config{
+		K:             10,
+		N:             3,
+		SortTransform: bundler.SortTransform,
+		Comparator:    comparator.Bytewise,
+		Blank:         blank.NewFilter(policy),
+		Stats:         &sorter.Stats{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dirSerializer, err := newSortSerializer(cfg, cancel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = partitionedSort(ctx, cancel, dirSerializer, []string{temp}, filepath.Join(dir, "output.txt"), cfg,
+		sorter.PartitionConfig{Partitions: 4, SampleSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dirSerializer, cfg
+}
+
+func TestPartitionedSort_Blank(t *testing.T) {
+	// Input is read twice, but blank lines are counted once
+	_, cfg := sortPartitioned(t, blank.Drop)
+	if cfg.Blank.Blank() != 3 {
+		t.Errorf("number of dropped blank lines is %d, but should be 3", cfg.Blank.Blank())
+	}
+
+	_, cfg = sortPartitioned(t, blank.Count)
+	if cfg.Blank.Blank() != 3 {
+		t.Errorf("number of counted blank lines is %d, but should be 3", cfg.Blank.Blank())
+	}
+}
+
+func TestPartitionedSort_Report(t *testing.T) {
+	// Input is read twice, once to sample keys, but only the sorting read is reported
+	dirSerializer, cfg := sortPartitioned(t, blank.Drop)
+	report := newRunReport(dirSerializer.Stats(), cfg.Blank, cfg.Stats, time.Now())
+	if report.Input.Files != 2 || report.Input.Lines != 9 {
+		t.Errorf("input has %d files and %d lines, but should have 2 files and 9 lines",
+			report.Input.Files, report.Input.Lines)
+	}
+	if report.BlankLines.Blank != 3 {
+		t.Errorf("number of blank lines is %d, but should be 3", report.BlankLines.Blank)
+	}
+	if report.Output.Lines != 6 {
+		t.Errorf("number of output lines is %d, but should be 6", report.Output.Lines)
+	}
+}
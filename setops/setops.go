@@ -0,0 +1,122 @@
+package setops
+
+import (
+	"AID/solution/comparator"
+	"AID/solution/merger"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Operation decides output of each distinct line by number of its occurrences in each input
+type Operation func(w *bufio.Writer, line string, counts []int) error
+
+// writeLine writes line with prefix
+func writeLine(w *bufio.Writer, prefix, line string) error {
+	_, err := w.WriteString(prefix)
+	if err == nil {
+		_, err = w.WriteString(line)
+	}
+	if err == nil {
+		err = w.WriteByte('\n')
+	}
+	return err
+}
+
+// Union writes lines present in any input
+func Union(w *bufio.Writer, line string, counts []int) error {
+	return writeLine(w, "", line)
+}
+
+// Intersect writes lines present in all inputs
+func Intersect(w *bufio.Writer, line string, counts []int) error {
+	for _, count := range counts {
+		if count == 0 {
+			return nil
+		}
+	}
+	return writeLine(w, "", line)
+}
+
+// Diff writes lines of the first input which are not present in other inputs
+func Diff(w *bufio.Writer, line string, counts []int) error {
+	if counts[0] == 0 {
+		return nil
+	}
+	for _, count := range counts[1:] {
+		if count > 0 {
+			return nil
+		}
+	}
+	return writeLine(w, "", line)
+}
+
+// Comm creates operation of two inputs which writes three columns like comm command:
+// lines only in the first input, lines only in the second input indented by a tab,
+// and lines in both inputs indented by two tabs; columns can be suppressed
+func Comm(suppress1, suppress2, suppress3 bool) Operation {
+	return func(w *bufio.Writer, line string, counts []int) error {
+		switch {
+		case counts[0] > 0 && counts[1] > 0:
+			if suppress3 {
+				return nil
+			}
+			prefix := "\t\t"
+			if suppress1 {
+				prefix = prefix[1:]
+			}
+			if suppress2 {
+				prefix = prefix[1:]
+			}
+			return writeLine(w, prefix, line)
+		case counts[0] > 0:
+			if suppress1 {
+				return nil
+			}
+			return writeLine(w, "", line)
+		default:
+			if suppress2 {
+				return nil
+			}
+			prefix := "\t"
+			if suppress1 {
+				prefix = ""
+			}
+			return writeLine(w, prefix, line)
+		}
+	}
+}
+
+// Run merges sorted read channels(chs) and writes result of op on distinct lines into w
+// Lines are distinct by keys of cmp, the first line of equal ones is written
+func Run(ctx context.Context, chs []<-chan []string, w io.Writer, cmp *comparator.Comparator, op Operation) error {
+	if len(chs) < 2 {
+		return fmt.Errorf("at least two inputs are needed")
+	}
+
+	writer := bufio.NewWriter(w)
+	cursor := merger.NewCursor(chs, cmp)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		line, counts, ok, err := cursor.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		err = op(writer, line, counts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
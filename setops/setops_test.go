@@ -0,0 +1,64 @@
+package setops
+
+import (
+	"AID/solution/comparator"
+	"bytes"
+	"context"
+	"testing"
+)
+
+// sendAll creates a channel for each source which sends its strings in a single batch
+func sendAll(sources ...[]string) []<-chan []string {
+	chs := make([]<-chan []string, 0, len(sources))
+	for _, source := range sources {
+		ch := make(chan []string, 1)
+		if len(source) > 0 {
+			ch <- source
+		}
+		close(ch)
+		chs = append(chs, ch)
+	}
+	return chs
+}
+
+func TestRun(t *testing.T) {
+	first := []string{"a", "b", "b", "d"}
+	second := []string{"b", "c", "d", "e"}
+	third := []string{"d", "e"}
+
+	tests := []struct {
+		name     string
+		op       Operation
+		sources  [][]string
+		expected string
+	}{
+		{"union", Union, [][]string{first, second, third}, "a\nb\nc\nd\ne\n"},
+		{"intersect", Intersect, [][]string{first, second, third}, "d\n"},
+		{"diff", Diff, [][]string{first, second}, "a\n"},
+		{"diff of three", Diff, [][]string{second, first, third}, "c\n"},
+		{"comm", Comm(false, false, false), [][]string{first, second}, "a\n\t\tb\n\tc\n\t\td\n\te\n"},
+		{"comm -1", Comm(true, false, false), [][]string{first, second}, "\tb\nc\n\td\ne\n"},
+		{"comm -12", Comm(true, true, false), [][]string{first, second}, "b\nd\n"},
+		{"comm -3", Comm(false, false, true), [][]string{first, second}, "a\n\tc\n\te\n"},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		err := Run(context.Background(), sendAll(test.sources...), &buffer, comparator.Bytewise, test.op)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if buffer.String() != test.expected {
+			t.Errorf("%s: output is %q, expected %q", test.name, buffer.String(), test.expected)
+		}
+	}
+}
+
+func TestRun_SingleInput(t *testing.T) {
+	var buffer bytes.Buffer
+	err := Run(context.Background(), sendAll([]string{"a"}), &buffer, comparator.Bytewise, Union)
+	if err == nil {
+		t.Error("expected error of single input")
+	}
+}
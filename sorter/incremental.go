@@ -0,0 +1,177 @@
+package sorter
+
+import (
+	"AID/solution/aggregator"
+	"AID/solution/inputserializer"
+	"AID/solution/merger"
+	"AID/solution/record"
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// writeMerged merges sorted channels and counted channels into file located at outputPath
+// If cfg.Count is set, lines of chs are counted and counts of equal keys in all channels are summed up,
+// otherwise counted must be empty
+// If cfg.Comparator is stable, lines are written without their origins
+// Output is summarized in cfg.Stats
+func writeMerged(ctx context.Context, chs []<-chan []string, counted []<-chan []string, outputPath string, cfg Config) (err error) {
+	if !cfg.Count && !cfg.Comparator.IsStable() {
+		return mergeToFile(ctx, chs, outputPath, cfg.Comparator, cfg.Stats)
+	}
+
+	output, closeOutput, err := createOutput(outputPath, cfg.Stats)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := closeOutput()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	if !cfg.Count {
+		// Origins of lines are removed from output
+		writer := bufio.NewWriter(output)
+		err = merger.MergeFunc(ctx, chs, cfg.Comparator, func(line string) error {
+			_, err := writer.WriteString(record.Line(line))
+			if err == nil {
+				err = writer.WriteByte('\n')
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+
+	all := make([]<-chan []string, 0, len(chs)+len(counted))
+	for _, ch := range chs {
+		all = append(all, aggregator.Count(ctx, ch, cfg.Comparator))
+	}
+	all = append(all, counted...)
+
+	writer := aggregator.NewWriter(output, cfg.Comparator)
+	err = merger.MergeFunc(ctx, all, aggregator.Comparator(cfg.Comparator), writer.AddLine)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// MinIncrementalFanIn is the least fan-in of IncrementalSort, one file of the final merge is the previous output
+// and at least two others are merged at once
+const MinIncrementalFanIn = 3
+
+// IncrementalSort sorts lines of readCh and merges them with the previous output located at outputPath,
+// which should be sorted by the same config
+// The previous output is replaced only after the new one is complete, if it doesn't exist only readCh is sorted
+func IncrementalSort(ctx context.Context, readCh <-chan []string, tempPaths []string, outputPath string, cfg Config) (err error) {
+	if cfg.Comparator.IsStable() {
+		return fmt.Errorf("previous output has no origins to be merged stably")
+	}
+	if cfg.FanIn() < MinIncrementalFanIn {
+		return fmt.Errorf("incremental sort needs fan-in of at least %d, not %d", MinIncrementalFanIn, cfg.FanIn())
+	}
+
+	// Readers are stopped if merge fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ts, err := cfg.NewTempStorage(tempPaths)
+	if err != nil {
+		log.Errorf("error in creating temporary storage: %v", err)
+		return err
+	}
+	defer func() {
+		cleanErr := ts.Clean()
+		if cleanErr != nil {
+			log.Errorf("error in cleaning TempStorage: %v", cleanErr)
+			if err == nil {
+				err = cleanErr
+			}
+		}
+	}()
+
+	start := time.Now()
+	err = StoreRuns(ctx, readCh, ts, cfg)
+	if err != nil {
+		log.Errorf("error in storing sorted bundles: %v", err)
+		return err
+	}
+	cfg.Stats.addStage("runs", start)
+	start = time.Now()
+
+	// One file of the final merge is the previous output
+	chs, err := merger.MergeLevels(ctx, ts, cfg.FanIn()-1, cfg.Comparator)
+	if err != nil {
+		return err
+	}
+
+	var previous []<-chan []string
+	mode := os.FileMode(0644)
+	info, err := os.Stat(outputPath)
+	switch {
+	case err == nil:
+		mode = info.Mode()
+		log.Infof("Merge with previous output %s", outputPath)
+		var ch <-chan []string
+		serializer := inputserializer.NewFileListSerializer([]string{outputPath})
+		if !cfg.Count {
+			// Counted lines are never blank, their keys were filtered when they were read
+			serializer.SetBlankFilter(cfg.Blank)
+		}
+		ch, err = serializer.GetSerializerCh(ctx)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, ch)
+	case os.IsNotExist(err):
+		log.Infof("No previous output at %s", outputPath)
+	default:
+		return err
+	}
+
+	// New output is written next to the previous one to be renamed over it
+	tempOutput, err := ioutil.TempFile(filepath.Dir(outputPath), filepath.Base(outputPath)+".*")
+	if err != nil {
+		return err
+	}
+	tempOutputPath := tempOutput.Name()
+	err = tempOutput.Close()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tempOutputPath)
+		}
+	}()
+
+	if cfg.Count {
+		err = writeMerged(ctx, chs, previous, tempOutputPath, cfg)
+	} else {
+		err = writeMerged(ctx, append(chs, previous...), nil, tempOutputPath, cfg)
+	}
+	if err != nil {
+		return err
+	}
+	cfg.Stats.addLevels(ts.Levels())
+	cfg.Stats.addOutputLevel(len(chs) + len(previous))
+	cfg.Stats.addStage("merge", start)
+
+	// Temporary files are only accessible by the owner
+	err = os.Chmod(tempOutputPath, mode)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempOutputPath, outputPath)
+}
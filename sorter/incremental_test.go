@@ -0,0 +1,149 @@
+package sorter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncrementalSort(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	outputPath := filepath.Join(dir, "out.txt")
+
+	// The first run has no previous output
+	err := IncrementalSort(ctx, sendLines(lines[:400]), []string{dir}, outputPath, testConfig())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	checkOutput(t, outputPath, lines[:400])
+
+	err = IncrementalSort(ctx, sendLines(lines[400:]), []string{dir}, outputPath, testConfig())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	checkOutput(t, outputPath, lines)
+}
+
+func TestIncrementalSort_Count(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg := testConfig()
+	cfg.Count = true
+	outputPath := filepath.Join(dir, "out.txt")
+
+	err := Sort(ctx, sendLines([]string{"b", "a", "c", "a"}), []string{dir}, outputPath, cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = IncrementalSort(ctx, sendLines([]string{"d", "a", "c"}), []string{dir}, outputPath, cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := "a\t3\nb\t1\nc\t2\nd\t1\n"
+	if string(content) != expected {
+		t.Errorf("output is %q, expected %q", content, expected)
+	}
+}
+
+func TestIncrementalSort_KeepsPreviousOnError(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	cfg := testConfig()
+	cfg.Count = true
+	outputPath := filepath.Join(dir, "out.txt")
+	previous := "a\t1\nb\tinvalid\n"
+	err := ioutil.WriteFile(outputPath, []byte(previous), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = IncrementalSort(context.Background(), sendLines([]string{"c"}), []string{dir}, outputPath, cfg)
+	if err == nil {
+		t.Error("expected error of invalid previous output")
+	}
+
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(content) != previous {
+		t.Errorf("previous output is changed to %q", content)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, info := range infos {
+		if info.Name() != "out.txt" && !info.IsDir() {
+			t.Errorf("partial output %s is remained", info.Name())
+		}
+	}
+}
+
+func TestIncrementalSort_FanIn(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(100)
+	outputPath := filepath.Join(dir, "out.txt")
+	err := ioutil.WriteFile(outputPath, []byte("previous\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fan-in of 1 besides the previous output would never reduce number of files
+	cfg := testConfig()
+	cfg.K = 2
+	err = IncrementalSort(ctx, sendLines(lines), []string{dir}, outputPath, cfg)
+	if err == nil {
+		t.Error("incremental sort with k 2 should fail")
+	}
+	if ctx.Err() != nil {
+		t.Error("incremental sort with k 2 should fail rather than merge forever")
+	}
+
+	cfg.K = MinIncrementalFanIn
+	err = IncrementalSort(ctx, sendLines(lines), []string{dir}, outputPath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOutput(t, outputPath, append([]string{"previous"}, lines...))
+}
@@ -0,0 +1,259 @@
+package sorter
+
+import (
+	"AID/solution/comparator"
+	"AID/solution/inputserializer"
+	"AID/solution/merger"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNotSorted is returned by MergeFiles when an input file is not sorted
+var ErrNotSorted = errors.New("input file is not sorted")
+
+// orderCheck verifies order of input channels, the first violation stops merge
+type orderCheck struct {
+	cancel context.CancelFunc
+	err    error
+	mutex  sync.Mutex
+}
+
+// Err returns the first order violation
+func (o *orderCheck) Err() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.err
+}
+
+func (o *orderCheck) setErr(err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.err == nil {
+		o.err = err
+		o.cancel()
+	}
+}
+
+// wrap returns channel of lines of ch which checks they are sorted by cmp
+func (o *orderCheck) wrap(ctx context.Context, path string, ch <-chan []string, cmp *comparator.Comparator) <-chan []string {
+	out := make(chan []string)
+
+	go func() {
+		defer close(out)
+
+		line := 0
+		var previous string
+		for batch := range ch {
+			for _, s := range batch {
+				line++
+				if line > 1 && cmp.Less(s, previous) {
+					o.setErr(fmt.Errorf("%w: %s line %d", ErrNotSorted, path, line))
+					return
+				}
+				previous = s
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- batch:
+			}
+		}
+	}()
+
+	return out
+}
+
+// countingWriter counts bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// mergedRun writes merge of sorted channels as a run of TempStorage
+type mergedRun struct {
+	ctx context.Context
+	chs []<-chan []string
+	cmp *comparator.Comparator
+}
+
+// WriteTo implements io.WriterTo
+func (r *mergedRun) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := merger.Merge(r.ctx, r.chs, cw, r.cmp)
+	return cw.n, err
+}
+
+// mergeToFile merges sorted channels into file located at outputPath, output is summarized in stats
+func mergeToFile(ctx context.Context, chs []<-chan []string, outputPath string, cmp *comparator.Comparator, stats *Stats) (err error) {
+	output, closeOutput, err := createOutput(outputPath, stats)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := closeOutput()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	return merger.Merge(ctx, chs, output, cmp)
+}
+
+// checkOutputNotInput checks whether file located at outputPath is one of files,
+// which would be truncated before it is read
+func checkOutputNotInput(outputPath string, files []string) error {
+	output, err := os.Stat(outputPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Links and different paths of the same file are detected too
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if os.SameFile(output, info) {
+			return fmt.Errorf("output %s is input file %s, it would be truncated before it is read", outputPath, file)
+		}
+	}
+	return nil
+}
+
+// MergeFiles merges sorted files into file located at outputPath, like sort -m
+// outputPath cannot be one of files
+// Files are checked to be sorted by cfg.Comparator unless trustSorted is set
+// If there are more than cfg.N files, groups of cfg.N files are merged into temporary files
+// striped across tempPaths, then they are merged level by level by TempStorage
+func MergeFiles(ctx context.Context, files []string, tempPaths []string, outputPath string, cfg Config, trustSorted bool) (err error) {
+	if len(files) == 0 {
+		return fmt.Errorf("no file to merge")
+	}
+	err = checkOutputNotInput(outputPath, files)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	check := &orderCheck{cancel: cancel}
+
+	// Order violation is the cause of a cancelled merge
+	defer func() {
+		if checkErr := check.Err(); checkErr != nil {
+			err = checkErr
+		}
+	}()
+
+	openFiles := func(paths []string) ([]<-chan []string, error) {
+		chs := make([]<-chan []string, 0, len(paths))
+		for _, path := range paths {
+			serializer := inputserializer.NewFileListSerializer([]string{path})
+			serializer.SetBlankFilter(cfg.Blank)
+			ch, err := serializer.GetSerializerCh(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if !trustSorted {
+				ch = check.wrap(ctx, path, ch, cfg.Comparator)
+			}
+			chs = append(chs, ch)
+		}
+		return chs, nil
+	}
+
+	if len(files) <= cfg.N {
+		chs, err := openFiles(files)
+		if err != nil {
+			return err
+		}
+		err = mergeToFile(ctx, chs, outputPath, cfg.Comparator, cfg.Stats)
+		if err == nil {
+			err = check.Err()
+		}
+		if err == nil {
+			cfg.Stats.addOutputLevel(len(chs))
+			cfg.Stats.addStage("merge", start)
+		}
+		if err != nil {
+			_ = os.Remove(outputPath)
+		}
+		return err
+	}
+
+	ts, err := cfg.NewTempStorage(tempPaths)
+	if err != nil {
+		log.Errorf("error in creating temporary storage: %v", err)
+		return err
+	}
+	defer func() {
+		cleanErr := ts.Clean()
+		if cleanErr != nil {
+			log.Errorf("error in cleaning TempStorage: %v", cleanErr)
+			if err == nil {
+				err = cleanErr
+			}
+		}
+	}()
+
+	// The first level is merged from input files, so they are not copied
+	for i := 0; i < len(files); i += cfg.N {
+		end := i + cfg.N
+		if end > len(files) {
+			end = len(files)
+		}
+
+		chs, err := openFiles(files[i:end])
+		if err != nil {
+			return err
+		}
+		err = ts.StoreNextFile(&mergedRun{ctx: ctx, chs: chs, cmp: cfg.Comparator})
+		if err != nil {
+			log.Errorf("error in merging input files: %v", err)
+			return err
+		}
+	}
+
+	if cfg.Stats.recordsOutput() {
+		// Output is summarized on the fly in the final merge
+		var chs []<-chan []string
+		chs, err = merger.MergeLevels(ctx, ts, cfg.N, cfg.Comparator)
+		if err == nil {
+			err = mergeToFile(ctx, chs, outputPath, cfg.Comparator, cfg.Stats)
+		}
+		if err == nil {
+			cfg.Stats.addLevels(ts.Levels())
+			cfg.Stats.addOutputLevel(len(chs))
+		}
+	} else {
+		err = merger.StartMerge(ctx, ts, outputPath, cfg.N, cfg.Comparator)
+		if err == nil {
+			cfg.Stats.addLevels(ts.Levels())
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		cfg.Stats.addStage("merge", start)
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package sorter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSortedFiles splits lines into count sorted files in dir
+func writeSortedFiles(t *testing.T, dir string, lines []string, count int) []string {
+	parts := make([][]string, count)
+	for i, line := range lines {
+		parts[i%count] = append(parts[i%count], line)
+	}
+
+	files := make([]string, count)
+	for i, part := range parts {
+		sort.Strings(part)
+		files[i] = filepath.Join(dir, "sorted"+strconv.Itoa(i))
+		err := ioutil.WriteFile(files[i], []byte(strings.Join(part, "\n")+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	outputPath := filepath.Join(dir, "out.txt")
+	// The second case needs multiple levels of TempStorage as N is 3
+	for _, count := range []int{3, 10} {
+		files := writeSortedFiles(t, dir, lines, count)
+		err := MergeFiles(ctx, files, []string{dir}, outputPath, testConfig(), false)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		checkOutput(t, outputPath, lines)
+	}
+}
+
+func TestMergeFiles_NotSorted(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	outputPath := filepath.Join(dir, "out.txt")
+	for _, count := range []int{3, 10} {
+		files := writeSortedFiles(t, dir, lines, count)
+		err := ioutil.WriteFile(files[count-1], []byte("b\na\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = MergeFiles(ctx, files, []string{dir}, outputPath, testConfig(), false)
+		if !errors.Is(err, ErrNotSorted) {
+			t.Errorf("expected ErrNotSorted with %d files, got %v", count, err)
+		}
+
+		// Order is not checked if input is trusted
+		err = MergeFiles(ctx, files, []string{dir}, outputPath, testConfig(), true)
+		if err != nil {
+			t.Errorf("unexpected error with trusted input: %v", err)
+		}
+	}
+}
+
+func TestMergeFiles_OutputIsInput(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(100)
+	files := writeSortedFiles(t, dir, lines, 3)
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(files[1], link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, outputPath := range []string{files[1], filepath.Join(dir, ".", "sorted1"), link} {
+		err := MergeFiles(ctx, files, []string{dir}, outputPath, testConfig(), false)
+		if err == nil {
+			t.Errorf("merge into %s should fail as it is an input file", outputPath)
+		}
+	}
+
+	// Input is not truncated
+	content, err := ioutil.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content) == 0 {
+		t.Errorf("input file %s is truncated", files[1])
+	}
+}
@@ -0,0 +1,309 @@
+package sorter
+
+import (
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"AID/solution/tempstorage"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// InputFunc opens a new channel of input lines, partitioned sort reads input twice:
+// once to sample keys and once to sort
+type InputFunc func(ctx context.Context) (<-chan []string, error)
+
+// PartitionConfig defines parameters of partitioned sort
+type PartitionConfig struct {
+	Partitions  int       // number of key ranges sorted in parallel
+	SampleSize  int       // number of keys sampled from input to pick splitters
+	SampleInput InputFunc // opens input to sample keys, e.g. by a serializer which isn't summarized, nil means input
+}
+
+// transformInput wraps input to apply transforms to each batch in order,
+// so lines are sampled and routed by their transformed keys
+func transformInput(input InputFunc, transforms []bundler.TransformFunc) InputFunc {
+	return func(ctx context.Context) (<-chan []string, error) {
+		readCh, err := input(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ch := make(chan []string)
+		go func() {
+			defer close(ch)
+			for batch := range readCh {
+				for _, t := range transforms {
+					batch = t(batch)
+				}
+				if len(batch) == 0 {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- batch:
+				}
+			}
+		}()
+		return ch, nil
+	}
+}
+
+// sampleSplitters picks up to partitions-1 splitter keys from a uniform sample of input keys
+func sampleSplitters(ctx context.Context, input InputFunc, pcfg PartitionConfig, cmp *comparator.Comparator) ([]string, error) {
+	readCh, err := input(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reservoir sampling, the seed is fixed to have same splitters for same input
+	r := rand.New(rand.NewSource(1))
+	sample := make([]string, 0, pcfg.SampleSize)
+	seen := 0
+	for batch := range readCh {
+		for _, s := range batch {
+			seen++
+			if len(sample) < pcfg.SampleSize {
+				sample = append(sample, cmp.Key(s))
+			} else if i := r.Intn(seen); i < pcfg.SampleSize {
+				sample[i] = cmp.Key(s)
+			}
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sample) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(sample)
+	splitters := make([]string, 0, pcfg.Partitions-1)
+	for i := 1; i < pcfg.Partitions; i++ {
+		splitters = append(splitters, sample[i*len(sample)/pcfg.Partitions])
+	}
+
+	log.Infof("Picked %d splitters from %d sampled keys of %d lines", len(splitters), len(sample), seen)
+
+	return splitters, nil
+}
+
+// route sends lines of readCh to partition channels by their keys,
+// partition i has keys in range [splitters[i-1], splitters[i])
+func route(ctx context.Context, readCh <-chan []string, chs []chan []string, splitters []string, cmp *comparator.Comparator) {
+	defer func() {
+		for _, ch := range chs {
+			close(ch)
+		}
+	}()
+
+	for batch := range readCh {
+		batches := make([][]string, len(chs))
+		for _, s := range batch {
+			key := cmp.Key(s)
+			i := sort.Search(len(splitters), func(i int) bool { return key < splitters[i] })
+			batches[i] = append(batches[i], s)
+		}
+
+		for i, b := range batches {
+			if len(b) == 0 {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case chs[i] <- b:
+			}
+		}
+	}
+}
+
+// divideLimit divides limit between partitions, each one has at least min
+func divideLimit(limit, partitions, min int) int {
+	limit /= partitions
+	if limit < min {
+		return min
+	}
+	return limit
+}
+
+// partitionConfig returns config of each one of partitions sorted in parallel,
+// limits of cfg are for all of them, so bundle size, open files, temporary files quota and I/O memory are divided
+// Lines are already transformed before routing, so partitions have no transform
+func partitionConfig(cfg Config, partitions int) Config {
+	partitionCfg := cfg
+	partitionCfg.Transforms = nil
+	partitionCfg.K = divideLimit(cfg.K, partitions, 2)
+	partitionCfg.N = divideLimit(cfg.N, partitions, 2)
+	if cfg.TempQuota > 0 {
+		partitionCfg.TempQuota = int64(divideLimit(int(cfg.TempQuota), partitions, 1))
+	}
+
+	if cfg.IOBlockSize > 0 {
+		// Each merged file has two blocks and the store file has one
+		memoryBudget := int64(2*cfg.FanIn()+1) * int64(cfg.IOBlockSize) / int64(partitions)
+		partitionCfg.IOBlockSize = int(memoryBudget / int64(2*partitionCfg.FanIn()+1))
+		if partitionCfg.IOBlockSize < tempstorage.MinIOBlockSize {
+			log.Warningf("I/O memory of %d bytes of each partition doesn't fit buffers of %d files of %d KB blocks, read-ahead is disabled",
+				memoryBudget, partitionCfg.FanIn(), tempstorage.MinIOBlockSize>>10)
+			partitionCfg.IOBlockSize = 0
+		}
+	}
+
+	return partitionCfg
+}
+
+// concatenate writes content of sorted partitions into file located at outputPath in order,
+// output is summarized in stats
+func concatenate(partitionPaths []string, outputPath string, stats *Stats) (err error) {
+	output, closeOutput, err := createOutput(outputPath, stats)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := closeOutput()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, partitionPath := range partitionPaths {
+		var partition *os.File
+		partition, err = os.Open(partitionPath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(output, partition)
+		closeErr := partition.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PartitionedSort sorts lines of input into file located at outputPath by splitting
+// key space into partitions by sampled splitters, partitions are sorted and merged
+// in parallel, each one by its own bundler and TempStorage, and their results are concatenated
+// Limits of cfg are divided between partitions, see partitionConfig
+func PartitionedSort(ctx context.Context, input InputFunc, tempPaths []string, outputPath string, cfg Config, pcfg PartitionConfig) error {
+	if pcfg.Partitions < 1 || pcfg.SampleSize < 1 {
+		return fmt.Errorf("number of partitions and sample size should be positive")
+	}
+
+	sampleInput := pcfg.SampleInput
+	if sampleInput == nil {
+		sampleInput = input
+	}
+	if len(cfg.Transforms) > 0 {
+		input = transformInput(input, cfg.Transforms)
+		sampleInput = transformInput(sampleInput, cfg.Transforms)
+	}
+
+	start := time.Now()
+	splitters, err := sampleSplitters(ctx, sampleInput, pcfg, cfg.Comparator)
+	if err != nil {
+		log.Errorf("error in sampling input: %v", err)
+		return err
+	}
+	cfg.Stats.addStage("sample", start)
+	partitions := len(splitters) + 1
+
+	partitionCfg := partitionConfig(cfg, partitions)
+
+	// Each partition has its own directory under every temporary path
+	partitionDirs := make([][]string, partitions)
+	partitionPaths := make([]string, partitions)
+	for i := range partitionDirs {
+		for _, tempPath := range tempPaths {
+			dir := path.Join(tempPath, fmt.Sprintf("partition-%d", i))
+			err = helper.MakeCleanDir(dir)
+			if err != nil {
+				return err
+			}
+			partitionDirs[i] = append(partitionDirs[i], dir)
+		}
+		partitionPaths[i] = path.Join(partitionDirs[i][0], "sorted")
+	}
+	defer func() {
+		for _, dirs := range partitionDirs {
+			for _, dir := range dirs {
+				_ = helper.CleanDir(dir)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	readCh, err := input(ctx)
+	if err != nil {
+		return err
+	}
+
+	chs := make([]chan []string, partitions)
+	for i := range chs {
+		chs[i] = make(chan []string)
+	}
+
+	errs := make([]error, partitions)
+	// Each partition has its own stats which are added up after they are complete
+	stats := make([]*Stats, partitions)
+	var wg sync.WaitGroup
+	wg.Add(partitions)
+	for i := 0; i < partitions; i++ {
+		go func(i int) {
+			defer wg.Done()
+			partitionCfg := partitionCfg
+			partitionCfg.Stats = cfg.Stats.newPartition()
+			stats[i] = partitionCfg.Stats
+			errs[i] = Sort(ctx, chs[i], partitionDirs[i], partitionPaths[i], partitionCfg)
+			if errs[i] != nil {
+				log.Errorf("error in sorting partition %d: %v", i, errs[i])
+				// Stop other partitions
+				cancel()
+			}
+		}(i)
+	}
+
+	route(ctx, readCh, chs, splitters, cfg.Comparator)
+	wg.Wait()
+
+	for _, err = range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, partitionStats := range stats {
+		cfg.Stats.addPartition(partitionStats)
+	}
+
+	log.Infof("Concatenate %d sorted partitions into %s", partitions, outputPath)
+
+	start = time.Now()
+	err = concatenate(partitionPaths, outputPath, cfg.Stats)
+	if err != nil {
+		return err
+	}
+	cfg.Stats.addStage("concatenate", start)
+	return nil
+}
@@ -0,0 +1,111 @@
+package sorter
+
+import (
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"AID/solution/inputserializer"
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IsSorted checks whether lines of file located at path are sorted by cmp
+func IsSorted(path string, cmp *comparator.Comparator) (sorted bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	reader := bufio.NewReader(file)
+	var previous string
+	for first := true; ; first = false {
+		line, err := helper.GetNextLine(reader)
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !first && cmp.Less(line, previous) {
+			return false, nil
+		}
+		previous = line
+	}
+}
+
+// SortedFiles returns paths of sorted content of files by cfg.Comparator, in order of files
+// Files which are sorted already are used themselves, others are sorted into temporary files
+// under tempPaths, clean removes the temporary files
+// If lines are changed by cfg, e.g. by transforms or by dropping blank lines, all files are sorted,
+// so every one of them is changed the same way
+func SortedFiles(ctx context.Context, files []string, tempPaths []string, cfg Config) (paths []string, clean func(), err error) {
+	var sortedPaths []string
+	clean = func() {
+		for _, sortedPath := range sortedPaths {
+			_ = os.Remove(sortedPath)
+		}
+	}
+	defer func() {
+		if err != nil {
+			clean()
+		}
+	}()
+
+	changesLines := len(cfg.Transforms) > 0 || (cfg.Blank != nil && cfg.Blank.Transform() != nil)
+
+	paths = make([]string, 0, len(files))
+	for _, file := range files {
+		if changesLines {
+			log.Infof("Lines of %s are changed, sort it first", file)
+		} else {
+			var sorted bool
+			sorted, err = IsSorted(file, cfg.Comparator)
+			if err != nil {
+				return nil, nil, err
+			}
+			if sorted {
+				paths = append(paths, file)
+				continue
+			}
+			log.Infof("%s is not sorted, sort it first", file)
+		}
+
+		var sortedFile *os.File
+		sortedFile, err = ioutil.TempFile(tempPaths[0], "sorted")
+		if err != nil {
+			return nil, nil, err
+		}
+		sortedPath := sortedFile.Name()
+		sortedPaths = append(sortedPaths, sortedPath)
+		err = sortedFile.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var readCh <-chan []string
+		readCh, err = inputserializer.NewFileListSerializer([]string{file}).GetSerializerCh(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = Sort(ctx, readCh, tempPaths, sortedPath, cfg)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, sortedPath)
+	}
+
+	return paths, clean, nil
+}
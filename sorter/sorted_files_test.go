@@ -0,0 +1,78 @@
+package sorter
+
+import (
+	"AID/solution/bundler"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSortedFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	lines := randomLines(100)
+	files := writeSortedFiles(t, dir, lines, 2)
+	unsorted := filepath.Join(dir, "unsorted")
+	err := ioutil.WriteFile(unsorted, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, clean, err := SortedFiles(context.Background(), append(files, unsorted), []string{dir}, testConfig())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if paths[0] != files[0] || paths[1] != files[1] {
+		t.Errorf("sorted files should be used themselves, got %v", paths[:2])
+	}
+	if paths[2] == unsorted {
+		t.Error("unsorted file should be sorted into a temporary file")
+	}
+	checkOutput(t, paths[2], lines)
+
+	clean()
+	if _, err = os.Stat(paths[2]); !os.IsNotExist(err) {
+		t.Errorf("temporary sorted file is not removed: %v", err)
+	}
+}
+
+func TestSortedFiles_Transforms(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// Both files are sorted already, but their lines are changed by transforms
+	files := []string{filepath.Join(dir, "1"), filepath.Join(dir, "2")}
+	contents := []string{"Beer\nWine\n", "apple\nbeer\n"}
+	for i, file := range files {
+		err := ioutil.WriteFile(file, []byte(contents[i]), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.Transforms = []bundler.TransformFunc{bundler.LowercaseTransform}
+	paths, clean, err := SortedFiles(context.Background(), files, []string{dir}, cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer clean()
+
+	for i, expected := range [][]string{{"beer", "wine"}, {"apple", "beer"}} {
+		if paths[i] == files[i] {
+			t.Errorf("%s should be transformed into a temporary file", files[i])
+		}
+		checkOutput(t, paths[i], expected)
+	}
+}
@@ -0,0 +1,170 @@
+package sorter
+
+import (
+	"AID/solution/blank"
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"AID/solution/helper"
+	"AID/solution/merger"
+	"AID/solution/tempstorage"
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Config defines parameters of sort pipeline
+type Config struct {
+	K             int                     // number of lines in each bundle
+	N             int                     // limit number of open files
+	ChanBufSize   int                     // size of buffered channels of temporary storage
+	IOBlockSize   int                     // size of temporary files I/O buffers, zero means default buffers
+	TempQuota     int64                   // limit of temporary files total size in bytes, zero means unlimited
+	Arena         bool                    // keep bundle lines in reused memory slabs, only for byte-wise order, without Transforms
+	Transforms    []bundler.TransformFunc // applied to each bundle in order before it is sorted, e.g. normalization
+	SortTransform bundler.TransformFunc   // sorts each bundle, should be consistent with Comparator
+	Comparator    *comparator.Comparator  // order of lines
+	Count         bool                    // output counted lines of distinct keys, see aggregator package
+	Blank         *blank.Filter           // applies blank lines policy to bundles and inputs of output, nil means lines are kept
+	RecordLimit   helper.RecordLimit      // limit of size of input records, applied by input serializers
+	SkipBinary    bool                    // skip input files which have NUL bytes in their first block
+	Stats         *Stats                  // summary of sort filled by sort functions, nil means sort is not summarized
+	Pressure      func() bool             // reports memory pressure, runs are spilled early and I/O buffers are shrunk, nil means never
+}
+
+// FanIn returns number of files are merged at once
+func (c *Config) FanIn() int {
+	if c.K < c.N {
+		return c.K
+	}
+	return c.N
+}
+
+// NewTempStorage creates TempStorage striped across tempPaths and configured by c
+func (c *Config) NewTempStorage(tempPaths []string) (*tempstorage.TempStorage, error) {
+	ts, err := tempstorage.NewStripedTempStorage(tempPaths, c.ChanBufSize)
+	if err != nil {
+		return nil, err
+	}
+	ts.SetQuota(c.TempQuota)
+	ts.SetIOBlockSize(c.IOBlockSize)
+	ts.SetPressureFunc(c.Pressure)
+	return ts, nil
+}
+
+// StoreRuns bundles lines of readCh into sorted runs and stores them in ts
+// Store processes are waited for and bundlers are stopped before it returns, whether it fails or not
+func StoreRuns(ctx context.Context, readCh <-chan []string, ts *tempstorage.TempStorage, cfg Config) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if cfg.Arena {
+		// Runs are written straight from the slab of reused run buffers
+		b := bundler.GetNewRunBundler(cfg.K)
+		b.SetSpillFunc(cfg.Pressure)
+		for run := range b.GetRunCh(ctx, readCh) {
+			err := ts.StoreNextFile(run)
+			if err != nil {
+				return err
+			}
+			b.Release(run)
+		}
+	} else {
+		b := bundler.GetNewBundler(cfg.K)
+		for _, t := range cfg.Transforms {
+			b.AddTransformFunc(t)
+		}
+		if cfg.Blank != nil {
+			// Blank lines made by transforms are dropped too
+			if t := cfg.Blank.Transform(); t != nil {
+				b.AddTransformFunc(t)
+			}
+		}
+		b.AddTransformFunc(cfg.SortTransform)
+		b.SetSpillFunc(cfg.Pressure)
+
+		bundlerCh := b.GetBundlerCh(ctx, readCh)
+
+		var wg sync.WaitGroup
+		// Runs being stored are completed before bundlers are stopped
+		defer wg.Wait()
+		for bundle := range bundlerCh {
+			wg.Add(1)
+			ch, err := ts.GetNextStoreCh(ctx, &wg)
+			if err != nil {
+				wg.Done()
+				return err
+			}
+
+			// Whole bundle is stored as a single batch
+			select {
+			case <-ctx.Done():
+				close(ch)
+				return ctx.Err()
+			case ch <- bundle:
+			}
+			close(ch)
+		}
+		wg.Wait()
+	}
+
+	// Runs of partial input are not stored as complete
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ts.Err()
+}
+
+// Sort sorts lines of readCh into file located at outputPath,
+// temporary files are striped across tempPaths
+func Sort(ctx context.Context, readCh <-chan []string, tempPaths []string, outputPath string, cfg Config) (err error) {
+	ts, err := cfg.NewTempStorage(tempPaths)
+	if err != nil {
+		log.Errorf("error in creating temporary storage: %v", err)
+		return err
+	}
+	defer func() {
+		cleanErr := ts.Clean()
+		if cleanErr != nil {
+			log.Errorf("error in cleaning TempStorage: %v", cleanErr)
+			if err == nil {
+				err = cleanErr
+			}
+		}
+	}()
+
+	start := time.Now()
+	err = StoreRuns(ctx, readCh, ts, cfg)
+	if err != nil {
+		log.Errorf("error in storing sorted bundles: %v", err)
+		return err
+	}
+	cfg.Stats.addStage("runs", start)
+
+	start = time.Now()
+	if cfg.Count || cfg.Comparator.IsStable() || cfg.Stats.recordsOutput() {
+		// Lines are counted, their origins are removed or output is summarized on the fly in the final merge
+		var chs []<-chan []string
+		chs, err = merger.MergeLevels(ctx, ts, cfg.FanIn(), cfg.Comparator)
+		if err != nil {
+			return err
+		}
+		err = writeMerged(ctx, chs, nil, outputPath, cfg)
+		if err != nil {
+			return err
+		}
+		cfg.Stats.addLevels(ts.Levels())
+		cfg.Stats.addOutputLevel(len(chs))
+		cfg.Stats.addStage("merge", start)
+		return nil
+	}
+
+	err = merger.StartMerge(ctx, ts, outputPath, cfg.FanIn(), cfg.Comparator)
+	if err != nil {
+		return err
+	}
+	cfg.Stats.addLevels(ts.Levels())
+	cfg.Stats.addStage("merge", start)
+	return nil
+}
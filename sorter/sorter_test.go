@@ -0,0 +1,429 @@
+package sorter
+
+import (
+	"AID/solution/blank"
+	"AID/solution/bundler"
+	"AID/solution/comparator"
+	"AID/solution/record"
+	"AID/solution/tempstorage"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func testConfig() Config {
+	return Config{
+		K:             10,
+		N:             3,
+		SortTransform: bundler.SortTransform,
+		Comparator:    comparator.Bytewise,
+	}
+}
+
+// randomLines generates n lines with duplicates in random order
+func randomLines(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = "term " + strconv.Itoa(r.Intn(n))
+	}
+	return lines
+}
+
+// sendLines creates channel which sends lines in batches of 7
+func sendLines(lines []string) <-chan []string {
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		for i := 0; i < len(lines); i += 7 {
+			end := i + 7
+			if end > len(lines) {
+				end = len(lines)
+			}
+			ch <- lines[i:end]
+		}
+	}()
+	return ch
+}
+
+// checkOutput checks whether file located at outputPath has sorted lines
+func checkOutput(t *testing.T, outputPath string, lines []string) {
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := make([]string, len(lines))
+	copy(expected, lines)
+	sort.Strings(expected)
+
+	result := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if strings.Join(result, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("output has %d lines which are not same as %d sorted input lines", len(result), len(expected))
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sorter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSort(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	outputPath := filepath.Join(dir, "out.txt")
+	for _, arena := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.Arena = arena
+		err := Sort(ctx, sendLines(lines), []string{dir}, outputPath, cfg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		checkOutput(t, outputPath, lines)
+	}
+}
+
+func TestStoreRuns_Cancel(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	for _, arena := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		// Input never ends, only cancel stops storing runs
+		readCh := make(chan []string)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case readCh <- []string{"b", "a", "c"}:
+				}
+			}
+		}()
+
+		ts, err := tempstorage.NewTempStorage(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := testConfig()
+		cfg.Arena = arena
+		done := make(chan error)
+		go func() {
+			done <- StoreRuns(ctx, readCh, ts, cfg)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		select {
+		case err = <-done:
+			if err != context.Canceled {
+				t.Errorf("error of cancelled StoreRuns with arena %v is %v, but should be %v", arena, err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("StoreRuns with arena %v is not stopped by cancel", arena)
+		}
+		_ = ts.Clean()
+	}
+}
+
+func TestPartitionedSort(t *testing.T) {
+	dir1, dir2 := tempDir(t), tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir1)
+		_ = os.RemoveAll(dir2)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	input := func(ctx context.Context) (<-chan []string, error) {
+		return sendLines(lines), nil
+	}
+
+	outputPath := filepath.Join(dir1, "out.txt")
+	for _, partitions := range []int{1, 4} {
+		err := PartitionedSort(ctx, input, []string{dir1, dir2}, outputPath, testConfig(),
+			PartitionConfig{Partitions: partitions, SampleSize: 100})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		checkOutput(t, outputPath, lines)
+	}
+
+	// Partition directories are removed
+	infos, err := ioutil.ReadDir(dir2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(infos) != 0 {
+		t.Errorf("%d files are remained in temporary directory", len(infos))
+	}
+}
+
+func TestPartitionedSort_Transforms(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Decomposed forms are normalized before lines are routed and sorted
+	var lines, expected []string
+	for i := 0; i < 300; i++ {
+		line := "m\u0308nchen " + strconv.Itoa(i%50)
+		lines = append(lines, line)
+		expected = append(expected, norm.NFC.String(line))
+	}
+	input := func(ctx context.Context) (<-chan []string, error) {
+		batch := make([]string, len(lines))
+		copy(batch, lines)
+		return sendLines(batch), nil
+	}
+
+	cfg := testConfig()
+	cfg.Transforms = []bundler.TransformFunc{bundler.NormalizeTransform(norm.NFC)}
+
+	outputPath := filepath.Join(dir, "out.txt")
+	for _, partitions := range []int{1, 3} {
+		err := PartitionedSort(ctx, input, []string{dir}, outputPath, cfg,
+			PartitionConfig{Partitions: partitions, SampleSize: 100})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		checkOutput(t, outputPath, expected)
+	}
+}
+
+func TestSort_Blank(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	outputPath := filepath.Join(dir, "out.txt")
+	sortLines := func(cfg Config, lines []string) string {
+		err := Sort(ctx, sendLines(lines), []string{dir}, outputPath, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	// Lines made blank by transforms are dropped
+	cfg := testConfig()
+	cfg.Transforms = []bundler.TransformFunc{bundler.StripPunctuationTransform}
+	cfg.Blank = blank.NewFilter(blank.Drop)
+	content := sortLines(cfg, []string{"beer", "...", "", "apple!", " "})
+	if content != "apple\nbeer\n" {
+		t.Errorf("output with dropped blank lines is %q", content)
+	}
+	if cfg.Blank.Blank() != 3 {
+		t.Errorf("%d blank lines are dropped, but should be 3", cfg.Blank.Blank())
+	}
+
+	// No run is stored if all lines are dropped
+	cfg.Blank = blank.NewFilter(blank.Drop)
+	content = sortLines(cfg, []string{"...", "", " "})
+	if content != "" {
+		t.Errorf("output of dropped lines is %q", content)
+	}
+
+	// Padded lines are kept next to their trimmed variants
+	cfg = testConfig()
+	cfg.Blank = blank.NewFilter(blank.Trim)
+	cfg.Comparator = cfg.Blank.Comparator(cfg.Comparator)
+	cfg.SortTransform = bundler.KeySortTransform(cfg.Comparator)
+	content = sortLines(cfg, []string{"beer  ", "apple", "beer", "  beer", "b"})
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 5 || lines[0] != "apple" || lines[1] != "b" || strings.TrimSpace(lines[4]) != "beer" {
+		t.Errorf("output with trimmed comparison is %q", content)
+	}
+}
+
+func TestSampleSplitters(t *testing.T) {
+	lines := randomLines(1000)
+	input := func(ctx context.Context) (<-chan []string, error) {
+		return sendLines(lines), nil
+	}
+
+	splitters, err := sampleSplitters(context.Background(), input, PartitionConfig{Partitions: 4, SampleSize: 100}, comparator.Bytewise)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(splitters) != 3 {
+		t.Errorf("number of splitters is %d, but should be 3", len(splitters))
+	}
+	if !sort.StringsAreSorted(splitters) {
+		t.Errorf("splitters are not sorted: %v", splitters)
+	}
+}
+
+func TestPartitionConfig(t *testing.T) {
+	ioMemory := func(c Config) int64 {
+		return int64(2*c.FanIn()+1) * int64(c.IOBlockSize)
+	}
+
+	cfg := Config{K: 400, N: 100, TempQuota: 1 << 30, IOBlockSize: 1 << 20}
+	partitionCfg := partitionConfig(cfg, 4)
+	if partitionCfg.K*4 > cfg.K {
+		t.Errorf("bundle size of 4 partitions is %d, but limit is %d", partitionCfg.K*4, cfg.K)
+	}
+	if partitionCfg.N*4 > cfg.N {
+		t.Errorf("open files of 4 partitions are %d, but limit is %d", partitionCfg.N*4, cfg.N)
+	}
+	if partitionCfg.TempQuota*4 > cfg.TempQuota {
+		t.Errorf("quota of 4 partitions is %d, but limit is %d", partitionCfg.TempQuota*4, cfg.TempQuota)
+	}
+	if partitionCfg.IOBlockSize < tempstorage.MinIOBlockSize || ioMemory(partitionCfg)*4 > ioMemory(cfg) {
+		t.Errorf("I/O memory of 4 partitions is %d by %d bytes blocks, but limit is %d",
+			ioMemory(partitionCfg)*4, partitionCfg.IOBlockSize, ioMemory(cfg))
+	}
+
+	// Buffers of blocks of minimum size don't fit in I/O memory of each partition
+	cfg = Config{K: 10, N: 3, IOBlockSize: tempstorage.MinIOBlockSize}
+	partitionCfg = partitionConfig(cfg, 4)
+	if partitionCfg.IOBlockSize != 0 {
+		t.Errorf("I/O block size of partitions is %d, but read-ahead should be disabled", partitionCfg.IOBlockSize)
+	}
+	if partitionCfg.TempQuota != 0 {
+		t.Errorf("quota of partitions is %d, but should be unlimited", partitionCfg.TempQuota)
+	}
+}
+
+func TestSort_Stable(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Lines are equal ignoring case, their input order is kept
+	var lines, tagged []string
+	for i := 0; i < 100; i++ {
+		line := "term"
+		if i%3 == 0 {
+			line = "TERM"
+		}
+		if i%7 == 0 {
+			line = "Term " + strconv.Itoa(i%2)
+		}
+		lines = append(lines, line)
+		tagged = append(tagged, record.Tag(line, uint32(i/10), uint64(i%10)))
+	}
+	// Input order is shuffled like when files are read concurrently
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(tagged), func(i, j int) { tagged[i], tagged[j] = tagged[j], tagged[i] })
+
+	cfg := testConfig()
+	cfg.Comparator = comparator.Stable(comparator.CaseFold)
+	cfg.SortTransform = bundler.KeySortTransform(cfg.Comparator)
+
+	outputPath := filepath.Join(dir, "out.txt")
+	err := Sort(ctx, sendLines(tagged), []string{dir}, outputPath, cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := make([]string, len(lines))
+	copy(expected, lines)
+	sort.SliceStable(expected, func(i, j int) bool { return comparator.CaseFold.Less(expected[i], expected[j]) })
+
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(content) != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("output is not stable sort of input:\n%s", content)
+	}
+}
+
+func TestSort_Deterministic(t *testing.T) {
+	dir1, dir2 := tempDir(t), tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir1)
+		_ = os.RemoveAll(dir2)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Lines equal ignoring case are ordered by the shape of merge tree
+	lines := randomLines(1000)
+	for i := range lines {
+		if i%2 == 0 {
+			lines[i] = strings.ToUpper(lines[i])
+		}
+	}
+
+	cfg := testConfig()
+	cfg.Comparator = comparator.CaseFold
+	cfg.SortTransform = bundler.KeySortTransform(cfg.Comparator)
+
+	var first []byte
+	for i := 0; i < 5; i++ {
+		outputPath := filepath.Join(dir1, "out"+strconv.Itoa(i)+".txt")
+		err := Sort(ctx, sendLines(lines), []string{dir1, dir2}, outputPath, cfg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		content, err := ioutil.ReadFile(outputPath)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if i == 0 {
+			first = content
+			continue
+		}
+		if !bytes.Equal(content, first) {
+			t.Errorf("output of run %d is different from the first one", i)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package sorter
+
+import (
+	"AID/solution/tempstorage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// Stage is time spent in a stage of sort
+type Stage struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// OutputStats summarizes the output file
+type OutputStats struct {
+	Lines    int64  `json:"lines"`
+	Bytes    int64  `json:"bytes"`
+	Checksum string `json:"sha256"` // SHA-256 of output content in hex
+}
+
+// Stats summarizes a sort, it is filled by sort functions if it is set in Config
+// Methods of nil Stats do nothing, so it is optional
+type Stats struct {
+	Runs   int                      `json:"runs"`   // number of sorted runs stored from input
+	Levels []tempstorage.LevelStats `json:"levels"` // stored levels, the last one is the output if it is merged from channels
+	Stages []Stage                  `json:"stages"` // time of stages in order, concurrent partitions report their longest ones
+	Output OutputStats              `json:"output"`
+
+	partition bool // stats of a partition, whose output is not the final one
+}
+
+// recordsOutput checks whether output should be summarized,
+// sort functions write output through outputWriter rather than moving the last temporary file then
+func (s *Stats) recordsOutput() bool {
+	return s != nil && !s.partition
+}
+
+// addStage adds time since start as stage name, time of an existing stage is kept if it is longer
+func (s *Stats) addStage(name string, start time.Time) {
+	if s == nil {
+		return
+	}
+	s.mergeStage(Stage{Name: name, Seconds: time.Since(start).Seconds()})
+}
+
+func (s *Stats) mergeStage(stage Stage) {
+	for i := range s.Stages {
+		if s.Stages[i].Name == stage.Name {
+			if stage.Seconds > s.Stages[i].Seconds {
+				s.Stages[i].Seconds = stage.Seconds
+			}
+			return
+		}
+	}
+	s.Stages = append(s.Stages, stage)
+}
+
+// addLevels adds stats of levels of a TempStorage, levels of same number are summed up
+func (s *Stats) addLevels(levels []tempstorage.LevelStats) {
+	if s == nil {
+		return
+	}
+	for _, level := range levels {
+		if level.Level == 0 {
+			s.Runs += level.Files
+		}
+		if level.Level >= len(s.Levels) {
+			s.Levels = append(s.Levels, tempstorage.LevelStats{Level: level.Level})
+		}
+		sum := &s.Levels[level.Level]
+		sum.Files += level.Files
+		sum.Bytes += level.Bytes
+		if level.FanIn > sum.FanIn {
+			sum.FanIn = level.FanIn
+		}
+	}
+}
+
+// addOutputLevel adds the output merged from fanIn channels as the last level
+func (s *Stats) addOutputLevel(fanIn int) {
+	if !s.recordsOutput() {
+		return
+	}
+	s.Levels = append(s.Levels, tempstorage.LevelStats{
+		Level: len(s.Levels),
+		Files: 1,
+		FanIn: fanIn,
+		Bytes: s.Output.Bytes,
+	})
+}
+
+// newPartition creates stats of a partition to be added to s by addPartition
+func (s *Stats) newPartition() *Stats {
+	if s == nil {
+		return nil
+	}
+	return &Stats{partition: true}
+}
+
+// addPartition adds stats of partition p to s
+func (s *Stats) addPartition(p *Stats) {
+	if s == nil || p == nil {
+		return
+	}
+	s.addLevels(p.Levels)
+	for _, stage := range p.Stages {
+		s.mergeStage(stage)
+	}
+}
+
+// outputWriter counts lines and bytes written to the output and computes their checksum
+type outputWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	lines int64
+	bytes int64
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.hash.Write(p[:n])
+	o.bytes += int64(n)
+	o.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	return n, err
+}
+
+// createOutput creates output file located at outputPath, content written to w is summarized
+// in stats when close is called if stats records output
+func createOutput(outputPath string, stats *Stats) (w io.Writer, close func() error, err error) {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !stats.recordsOutput() {
+		return file, file.Close, nil
+	}
+
+	ow := &outputWriter{w: file, hash: sha256.New()}
+	close = func() error {
+		stats.Output = OutputStats{
+			Lines:    ow.lines,
+			Bytes:    ow.bytes,
+			Checksum: hex.EncodeToString(ow.hash.Sum(nil)),
+		}
+		return file.Close()
+	}
+	return ow, close, nil
+}
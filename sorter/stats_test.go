@@ -0,0 +1,99 @@
+package sorter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// checkOutputStats checks whether stats summarizes file located at outputPath
+func checkOutputStats(t *testing.T, stats *Stats, outputPath string, lines int) {
+	content, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	expected := OutputStats{Lines: int64(lines), Bytes: int64(len(content)), Checksum: hex.EncodeToString(sum[:])}
+	if stats.Output != expected {
+		t.Errorf("output stats are %+v, but should be %+v", stats.Output, expected)
+	}
+}
+
+func TestSort_Stats(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(100)
+	cfg := testConfig()
+	cfg.Stats = &Stats{}
+	outputPath := filepath.Join(dir, "out.txt")
+	err := Sort(ctx, sendLines(lines), []string{dir}, outputPath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOutput(t, outputPath, lines)
+	checkOutputStats(t, cfg.Stats, outputPath, len(lines))
+
+	// Runs of 10 lines are merged 3 by 3 level by level into the output
+	levels := cfg.Stats.Levels
+	if cfg.Stats.Runs < 10 || len(levels) < 2 || levels[0].Files != cfg.Stats.Runs || levels[len(levels)-1].Files != 1 {
+		t.Fatalf("%d runs are merged in levels %+v", cfg.Stats.Runs, levels)
+	}
+	for i, level := range levels {
+		if level.Level != i || level.Bytes != cfg.Stats.Output.Bytes || (i > 0 && (level.FanIn < 2 || level.FanIn > cfg.N)) {
+			t.Errorf("level %d is %+v", i, level)
+		}
+	}
+	if len(cfg.Stats.Stages) != 2 || cfg.Stats.Stages[0].Name != "runs" || cfg.Stats.Stages[1].Name != "merge" {
+		t.Errorf("stages are %+v", cfg.Stats.Stages)
+	}
+}
+
+func TestPartitionedSort_Stats(t *testing.T) {
+	dir := tempDir(t)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := randomLines(1000)
+	input := func(ctx context.Context) (<-chan []string, error) {
+		return sendLines(lines), nil
+	}
+
+	cfg := testConfig()
+	cfg.Stats = &Stats{}
+	outputPath := filepath.Join(dir, "out.txt")
+	err := PartitionedSort(ctx, input, []string{dir}, outputPath, cfg, PartitionConfig{Partitions: 4, SampleSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOutputStats(t, cfg.Stats, outputPath, len(lines))
+
+	// Runs of partitions are added up, each partition has bundles of 2 lines
+	if cfg.Stats.Runs < len(lines)/2 {
+		t.Errorf("number of runs is %d", cfg.Stats.Runs)
+	}
+	if cfg.Stats.Levels[0].Files != cfg.Stats.Runs || cfg.Stats.Levels[0].Bytes != cfg.Stats.Output.Bytes {
+		t.Errorf("first level of partitions is %+v", cfg.Stats.Levels[0])
+	}
+	var names []string
+	for _, stage := range cfg.Stats.Stages {
+		names = append(names, stage.Name)
+	}
+	if len(names) != 4 || names[0] != "sample" || names[3] != "concatenate" {
+		t.Errorf("stages are %v", names)
+	}
+}
@@ -0,0 +1,118 @@
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Paths of files memory information is read from, variables to be replaced in tests
+var (
+	meminfoPath       = "/proc/meminfo"
+	cgroupV2MemoryMax = "/sys/fs/cgroup/memory.max"
+	cgroupV1MemoryMax = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	processStatusPath = "/proc/self/status"
+)
+
+// cgroupV1Unlimited is the least limit considered unlimited, v1 reports no limit as a huge page aligned number
+const cgroupV1Unlimited = uint64(1) << 62
+
+// parseMeminfo returns MemAvailable of meminfo content in bytes
+func parseMeminfo(r io.Reader) (uint64, error) {
+	return parseMemoryField(r, "MemAvailable")
+}
+
+// parseMemoryField returns value of field of /proc memory information content in bytes
+func parseMemoryField(r io.Reader, name string) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != name+":" {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			value <<= 10
+		}
+		return value, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no %s in memory information", name)
+}
+
+// MemAvailable returns memory available for starting new applications in bytes, read from /proc/meminfo
+func MemAvailable() (uint64, error) {
+	file, err := os.Open(meminfoPath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return parseMeminfo(file)
+}
+
+// PeakMemory returns peak resident set size of the process in bytes, read from /proc/self/status,
+// or memory obtained from the OS by Go runtime if it is not available
+func PeakMemory() uint64 {
+	file, err := os.Open(processStatusPath)
+	if err == nil {
+		defer func() {
+			_ = file.Close()
+		}()
+		if peak, err := parseMemoryField(file, "VmHWM"); err == nil {
+			return peak
+		}
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys
+}
+
+// readCgroupValue reads a single value cgroup file, ok is false if the file doesn't exist or value is "max"
+func readCgroupValue(path string) (value uint64, ok bool, err error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	text := strings.TrimSpace(string(content))
+	if text == "max" {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid value in %s: %v", path, err)
+	}
+	return value, true, nil
+}
+
+// CgroupMemoryLimit returns memory limit of cgroup of the process in bytes, cgroup v2 is checked then v1
+// ok is false if there is no limit
+func CgroupMemoryLimit() (limit uint64, ok bool, err error) {
+	limit, ok, err = readCgroupValue(cgroupV2MemoryMax)
+	if err != nil || ok {
+		return
+	}
+
+	limit, ok, err = readCgroupValue(cgroupV1MemoryMax)
+	if ok && limit >= cgroupV1Unlimited {
+		return 0, false, nil
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package sysinfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	content := "MemTotal:       16314788 kB\nMemFree:         1234567 kB\nMemAvailable:    8000000 kB\n"
+	available, err := parseMeminfo(strings.NewReader(content))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if available != 8000000<<10 {
+		t.Errorf("available memory is %d", available)
+	}
+
+	_, err = parseMeminfo(strings.NewReader("MemTotal: 1 kB\n"))
+	if err == nil {
+		t.Error("expected error of missing MemAvailable")
+	}
+}
+
+func TestMemAvailable(t *testing.T) {
+	if _, err := os.Stat(meminfoPath); err != nil {
+		t.Skip("no meminfo")
+	}
+	available, err := MemAvailable()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if available == 0 {
+		t.Error("available memory should not be zero")
+	}
+}
+
+func TestPeakMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	defer func(path string) {
+		processStatusPath = path
+	}(processStatusPath)
+	processStatusPath = filepath.Join(dir, "status")
+	err = ioutil.WriteFile(processStatusPath, []byte("Name:\tsolution\nVmPeak:\t  900000 kB\nVmHWM:\t  123456 kB\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak := PeakMemory(); peak != 123456<<10 {
+		t.Errorf("peak memory is %d", peak)
+	}
+
+	// Memory obtained by runtime is used without status file
+	processStatusPath = filepath.Join(dir, "missing")
+	if PeakMemory() == 0 {
+		t.Error("peak memory should not be zero")
+	}
+}
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldV2, oldV1 := cgroupV2MemoryMax, cgroupV1MemoryMax
+	defer func() {
+		cgroupV2MemoryMax, cgroupV1MemoryMax = oldV2, oldV1
+		_ = os.RemoveAll(dir)
+	}()
+	cgroupV2MemoryMax, cgroupV1MemoryMax = filepath.Join(dir, "v2"), filepath.Join(dir, "v1")
+
+	tests := []struct {
+		v2, v1 string // empty means file doesn't exist
+		limit  uint64
+		ok     bool
+	}{
+		{"", "", 0, false},
+		{"1073741824\n", "", 1 << 30, true},
+		{"max\n", "", 0, false},
+		{"", "536870912\n", 1 << 29, true},
+		{"", "9223372036854771712\n", 0, false},
+	}
+
+	for _, test := range tests {
+		for path, content := range map[string]string{cgroupV2MemoryMax: test.v2, cgroupV1MemoryMax: test.v1} {
+			_ = os.Remove(path)
+			if content != "" {
+				if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		limit, ok, err := CgroupMemoryLimit()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if limit != test.limit || ok != test.ok {
+			t.Errorf("limit of v2 %q and v1 %q is %d %v, but should be %d %v", test.v2, test.v1, limit, ok, test.limit, test.ok)
+		}
+	}
+}
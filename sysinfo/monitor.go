@@ -0,0 +1,121 @@
+package sysinfo
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryBudget returns memory the process can use in bytes,
+// which is the least of available memory and cgroup v1/v2 memory limit
+func MemoryBudget() (uint64, error) {
+	budget, err := MemAvailable()
+	if err != nil {
+		return 0, err
+	}
+
+	limit, ok, err := CgroupMemoryLimit()
+	if err != nil {
+		return 0, err
+	}
+	if ok && limit < budget {
+		budget = limit
+	}
+	return budget, nil
+}
+
+// MemoryMonitor watches live heap of the process by runtime.ReadMemStats,
+// memory is under pressure when usage reaches a threshold of the limit,
+// and it is relieved only when usage is below a lower release threshold
+type MemoryMonitor struct {
+	limit     uint64        // memory limit in bytes
+	threshold uint64        // usage from which memory is under pressure in bytes
+	release   uint64        // usage below which pressure is relieved in bytes
+	interval  time.Duration // period of reading memory stats
+	used      uint64        // memory used at last read, accessed atomically
+	pressure  int32         // 1 if memory is under pressure, accessed atomically
+	done      chan struct{}
+}
+
+// releaseRatio is the ratio of release threshold to the threshold of pressure,
+// so pressure doesn't flap while usage is around the threshold
+const releaseRatio = 0.9
+
+// NewMemoryMonitor creates MemoryMonitor of limit bytes which is under pressure from ratio of limit
+// Start should be called to start watching
+func NewMemoryMonitor(limit uint64, ratio float64, interval time.Duration) *MemoryMonitor {
+	threshold := uint64(float64(limit) * ratio)
+	return &MemoryMonitor{
+		limit:     limit,
+		threshold: threshold,
+		release:   uint64(float64(threshold) * releaseRatio),
+		interval:  interval,
+		done:      make(chan struct{}),
+	}
+}
+
+// memoryUsed returns bytes of heap spans in use, idle heap which is not returned to OS yet is not counted,
+// so usage drops after garbage collection
+func memoryUsed() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// check reads memory stats and updates pressure
+func (m *MemoryMonitor) check() {
+	m.update(memoryUsed())
+}
+
+// update updates pressure by used bytes
+func (m *MemoryMonitor) update(used uint64) {
+	atomic.StoreUint64(&m.used, used)
+
+	pressure := atomic.LoadInt32(&m.pressure)
+	if used >= m.threshold {
+		pressure = 1
+	} else if used < m.release {
+		pressure = 0
+	}
+	if atomic.SwapInt32(&m.pressure, pressure) != pressure {
+		if pressure == 1 {
+			log.Warningf("Memory is under pressure: %d MB used of %d MB limit", used>>20, m.limit>>20)
+		} else {
+			log.Infof("Memory pressure is relieved: %d MB used of %d MB limit", used>>20, m.limit>>20)
+		}
+	}
+}
+
+// Start starts watching memory in background
+func (m *MemoryMonitor) Start() {
+	m.check()
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+}
+
+// Stop stops watching memory
+func (m *MemoryMonitor) Stop() {
+	close(m.done)
+}
+
+// UnderPressure checks whether memory used at last read is above the threshold
+func (m *MemoryMonitor) UnderPressure() bool {
+	return atomic.LoadInt32(&m.pressure) == 1
+}
+
+// Used returns memory used at last read in bytes
+func (m *MemoryMonitor) Used() uint64 {
+	return atomic.LoadUint64(&m.used)
+}
@@ -0,0 +1,60 @@
+package sysinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryMonitor(t *testing.T) {
+	// Any process uses more than one byte
+	m := NewMemoryMonitor(2, 0.5, time.Millisecond)
+	m.Start()
+	defer m.Stop()
+	if !m.UnderPressure() || m.Used() == 0 {
+		t.Errorf("memory should be under pressure with %d bytes used", m.Used())
+	}
+
+	relaxed := NewMemoryMonitor(1<<62, 0.9, time.Millisecond)
+	relaxed.Start()
+	defer relaxed.Stop()
+	time.Sleep(5 * time.Millisecond)
+	if relaxed.UnderPressure() {
+		t.Error("memory should not be under pressure")
+	}
+}
+
+func TestMemoryMonitor_Release(t *testing.T) {
+	m := NewMemoryMonitor(1000, 0.5, time.Millisecond)
+
+	// Pressure starts at threshold and is relieved only below release threshold
+	for _, step := range []struct {
+		used     uint64
+		pressure bool
+	}{
+		{400, false},
+		{500, true},
+		{460, true},
+		{449, false},
+		{480, false},
+	} {
+		m.update(step.used)
+		if m.UnderPressure() != step.pressure {
+			t.Errorf("pressure of %d bytes used is %v, but should be %v", step.used, m.UnderPressure(), step.pressure)
+		}
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	available, err := MemAvailable()
+	if err != nil {
+		t.Skip("no meminfo")
+	}
+	budget, err := MemoryBudget()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if budget == 0 || budget > available {
+		t.Errorf("budget is %d bytes while %d bytes are available", budget, available)
+	}
+}
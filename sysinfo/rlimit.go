@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package sysinfo
+
+import (
+	"syscall"
+)
+
+// OpenFileLimit returns soft limit of number of open files of the process
+func OpenFileLimit() (uint64, error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, err
+	}
+	return uint64(limit.Cur), nil
+}
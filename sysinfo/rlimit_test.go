@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package sysinfo
+
+import (
+	"testing"
+)
+
+func TestOpenFileLimit(t *testing.T) {
+	limit, err := OpenFileLimit()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if limit == 0 {
+		t.Error("limit of open files should not be zero")
+	}
+}
@@ -0,0 +1,10 @@
+package sysinfo
+
+import (
+	"errors"
+)
+
+// OpenFileLimit returns soft limit of number of open files of the process
+func OpenFileLimit() (uint64, error) {
+	return 0, errors.New("not supported on windows")
+}
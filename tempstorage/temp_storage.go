@@ -6,130 +6,233 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"sync"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 )
 
 // TempStorage store temporary files data structure
+// Files of each level are striped across root directories round-robin
 type TempStorage struct {
-	path                      string   // path of root directory
-	readLevel                 int      // level from which data would read
-	storeLevel                int      // level to which data would write
-	readDirPath, storeDirPath string   // keep read and store paths to generate once and use multiple times
-	readDirFile               *os.File // read directory os.File to go through files in read directory
-	storeFileCounter          int      // number of files has been created in store directory, used to create next ones
-	chanBuffSize              int      // size of buffered channels will be produced by TempStorage
+	paths                       []string     // paths of root directories
+	readLevel                   int          // level from which data would read
+	storeLevel                  int          // level to which data would write
+	readDirPaths, storeDirPaths []string     // keep read and store paths of each root to generate once and use multiple times
+	storeFileCounter            int          // number of files has been created in store directories, used to create next ones
+	readFileCount               int          // number of files in read directories
+	readFileIndex               int          // run number of the next file to be read, files are read in order of run numbers
+	chanBuffSize                int          // size of buffered channels will be produced by TempStorage
+	ioBlockSize                 int          // size of read-ahead and write buffers of files, zero means default buffers
+	quota                       int64        // limit of total size of temporary files in bytes, zero means unlimited
+	usedBytes                   int64        // total size of temporary files currently stored, accessed atomically
+	readGroupSize               int64        // total size of files returned by last GetNextReadChs, they are merged into next store file
+	err                         error        // the first error happened in background store processes
+	pressure                    func() bool  // reports memory pressure, files opened under pressure have smaller buffers
+	storeBytes                  int64        // bytes written to files of store level, accessed atomically
+	storeFanIn                  int          // largest number of files read to be merged into a file of store level
+	levels                      []LevelStats // stats of levels which are no longer stored
+	errMutex                    sync.Mutex
 }
 
-// NewTempStorage creates new TempStorage module
-func NewTempStorage(path string, chanBuffSize int) (*TempStorage, error) {
-	file, err := os.Stat(path)
-	if err != nil {
-		return nil, err
+const (
+	// MinIOBlockSize is the smallest block size chosen by IOBlockSizeFromBudget
+	MinIOBlockSize = 64 << 10
+	// MaxIOBlockSize is the largest block size chosen by IOBlockSizeFromBudget
+	MaxIOBlockSize = 16 << 20
+)
+
+// IOBlockSizeFromBudget computes I/O block size to fit buffers of a merge with fan-in files
+// in memoryBudget bytes, each read file has two blocks (double buffering) and the store file has one
+// Blocks are not smaller than MinIOBlockSize, so fan-in should not be more than MaxFanInFromBudget
+func IOBlockSizeFromBudget(memoryBudget int64, fanIn int) int {
+	size := memoryBudget / int64(2*fanIn+1)
+	if size < MinIOBlockSize {
+		return MinIOBlockSize
+	}
+	if size > MaxIOBlockSize {
+		return MaxIOBlockSize
 	}
+	return int(size)
+}
 
-	if !file.IsDir() {
-		err = fmt.Errorf("%s should be a directory", path)
-		return nil, err
+// MaxFanInFromBudget returns the largest fan-in whose merge buffers of MinIOBlockSize blocks fit in memoryBudget bytes,
+// each read file has two blocks and the store file has one
+func MaxFanInFromBudget(memoryBudget int64) int {
+	return int((memoryBudget/MinIOBlockSize - 1) / 2)
+}
+
+// SetIOBlockSize sets size of read-ahead and write buffers of files,
+// files are read ahead in background by two blocks of this size
+// zero means default buffers without read-ahead
+func (ts *TempStorage) SetIOBlockSize(size int) {
+	ts.ioBlockSize = size
+}
+
+// pressureIOBlockDivisor is the ratio of I/O block size to the one of files opened under memory pressure
+const pressureIOBlockDivisor = 4
+
+// SetPressureFunc sets f to check memory pressure, I/O buffers of files opened under pressure are shrunk
+func (ts *TempStorage) SetPressureFunc(f func() bool) {
+	ts.pressure = f
+}
+
+// currentIOBlockSize returns I/O block size of a file opened now, which is shrunk under memory pressure
+func (ts *TempStorage) currentIOBlockSize() int {
+	if ts.ioBlockSize == 0 || ts.pressure == nil || !ts.pressure() {
+		return ts.ioBlockSize
 	}
-	isWritable, err := helper.IsWritableDir(path)
-	if err != nil {
-		return nil, err
+
+	size := ts.ioBlockSize / pressureIOBlockDivisor
+	if size < MinIOBlockSize {
+		size = MinIOBlockSize
 	}
-	if !isWritable {
-		err = fmt.Errorf("%s should be a writable directory", path)
-		return nil, err
+	if size < ts.ioBlockSize {
+		log.Debugf("I/O block size is shrunk to %d bytes under memory pressure", size)
+	}
+	return size
+}
+
+// NewTempStorage creates new TempStorage module
+func NewTempStorage(path string, chanBuffSize int) (*TempStorage, error) {
+	return NewStripedTempStorage([]string{path}, chanBuffSize)
+}
+
+// NewStripedTempStorage creates new TempStorage module which stripes files across paths,
+// paths are expected to be on different disks
+func NewStripedTempStorage(paths []string, chanBuffSize int) (*TempStorage, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one path is needed")
+	}
+
+	for _, path := range paths {
+		file, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !file.IsDir() {
+			err = fmt.Errorf("%s should be a directory", path)
+			return nil, err
+		}
+		isWritable, err := helper.IsWritableDir(path)
+		if err != nil {
+			return nil, err
+		}
+		if !isWritable {
+			err = fmt.Errorf("%s should be a writable directory", path)
+			return nil, err
+		}
 	}
 	ts := &TempStorage{
-		path:             path,
+		paths:            paths,
 		readLevel:        -1,
 		storeLevel:       0,
 		storeFileCounter: 0,
 		chanBuffSize:     chanBuffSize,
 	}
-	// Create zero level (initial input) store directory
-	levelPath, err := ts.getTempLevelPath(ts.storeLevel)
+	// Create zero level (initial input) store directories
+	levelPaths, err := ts.getTempLevelPaths(ts.storeLevel)
 	if err != nil {
 		return nil, err
 	}
-	ts.storeDirPath = levelPath
+	ts.storeDirPaths = levelPaths
 
-	err = helper.MakeCleanDir(levelPath)
-	if err != nil {
-		return nil, err
+	for _, levelPath := range levelPaths {
+		err = helper.MakeCleanDir(levelPath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	log.Infof("TempStorage ready to store at level %d: %s", ts.storeLevel, ts.storeDirPath)
+	log.Infof("TempStorage ready to store at level %d: %v", ts.storeLevel, ts.storeDirPaths)
 
 	return ts, nil
 
 }
 
-func (ts *TempStorage) getTempLevelPath(level int) (string, error) {
+func (ts *TempStorage) getTempLevelPath(root string, level int) (string, error) {
 	if level < 0 {
 		err := fmt.Errorf("level %d cannot be less than zero", level)
 		return "", err
 	}
 
-	result := path.Join(ts.path, strconv.Itoa(level))
+	result := path.Join(root, strconv.Itoa(level))
 
 	return result, nil
 }
 
-// SetupNextLevel moves Temporary Storage one step further
-func (ts *TempStorage) SetupNextLevel() error {
-	// Clean previous read directory
-	if ts.readLevel >= 0 {
-		err := ts.readDirFile.Close()
+// getTempLevelPaths returns level directory path under each root directory
+func (ts *TempStorage) getTempLevelPaths(level int) ([]string, error) {
+	result := make([]string, 0, len(ts.paths))
+	for _, root := range ts.paths {
+		levelPath, err := ts.getTempLevelPath(root, level)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		result = append(result, levelPath)
+	}
 
-		readPath, err := ts.getTempLevelPath(ts.readLevel)
-		if err != nil {
-			return err
-		}
+	return result, nil
+}
 
-		err = helper.CleanDir(readPath)
-		if err != nil {
-			return err
+// SetupNextLevel moves Temporary Storage one step further
+func (ts *TempStorage) SetupNextLevel() error {
+	// Clean previous read directories
+	if ts.readLevel >= 0 {
+		for _, readPath := range ts.readDirPaths {
+			err := helper.CleanDir(readPath)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
+	ts.levels = append(ts.levels, ts.storeLevelStats())
+	atomic.StoreInt64(&ts.storeBytes, 0)
+	ts.storeFanIn = 0
+
 	ts.readLevel++
 	ts.storeLevel++
 
 	// Initialize read at readLevel
-	ts.readDirPath = ts.storeDirPath
-
-	readDirFile, err := os.Open(ts.readDirPath)
+	ts.readDirPaths = ts.storeDirPaths
+	ts.readFileCount = ts.storeFileCounter
+	ts.readFileIndex = 0
 
-	log.Infof("TempStorage ready to read at level %d: %s", ts.readLevel, ts.readDirPath)
-
-	if err != nil {
-		return err
-	}
-	ts.readDirFile = readDirFile
+	log.Infof("TempStorage ready to read at level %d: %v", ts.readLevel, ts.readDirPaths)
 
 	// Initialize store at storeLevel
-	storePath, err := ts.getTempLevelPath(ts.storeLevel)
+	storePaths, err := ts.getTempLevelPaths(ts.storeLevel)
 	if err != nil {
 		return err
 	}
-	ts.storeDirPath = storePath
+	ts.storeDirPaths = storePaths
 	ts.storeFileCounter = 0
 
-	err = helper.MakeCleanDir(storePath)
-	if err != nil {
-		log.Errorf("error on creating store path %s: %v", storePath, err)
-		return err
+	for _, storePath := range storePaths {
+		err = helper.MakeCleanDir(storePath)
+		if err != nil {
+			log.Errorf("error on creating store path %s: %v", storePath, err)
+			return err
+		}
 	}
 
-	log.Infof("TempStorage ready to store at level %d: %s", ts.storeLevel, ts.storeDirPath)
+	log.Infof("TempStorage ready to store at level %d: %v", ts.storeLevel, ts.storeDirPaths)
 
 	return nil
 }
 
+// ReadFileCount returns number of files stored in read level
+func (ts *TempStorage) ReadFileCount() int {
+	return ts.readFileCount
+}
+
+// StoreFileCount returns number of files stored in store level
+func (ts *TempStorage) StoreFileCount() int {
+	return ts.storeFileCounter
+}
+
 // HasSingleStoredFile check whether one and jus one file is stored at
 // store directory
 // result would be true if there is just one in store directory
@@ -137,8 +240,8 @@ func (ts *TempStorage) SetupNextLevel() error {
 func (ts *TempStorage) HasSingleStoredFile() (result bool, storedFilePath string) {
 	if ts.storeFileCounter == 1 {
 		result = true
-		// By convention name are number starting from zero
-		storedFilePath = path.Join(ts.storeDirPath, "0")
+		// By convention name are number starting from zero, which is stored in the first root
+		storedFilePath = path.Join(ts.storeDirPaths[0], "0")
 	}
 	return
 }
@@ -146,15 +249,20 @@ func (ts *TempStorage) HasSingleStoredFile() (result bool, storedFilePath string
 // Clean store and read directories
 func (ts *TempStorage) Clean() error {
 	if ts.readLevel >= 0 {
-		err := helper.CleanDir(ts.readDirPath)
+		for _, readPath := range ts.readDirPaths {
+			err := helper.CleanDir(readPath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, storePath := range ts.storeDirPaths {
+		err := helper.CleanDir(storePath)
 		if err != nil {
 			return err
 		}
 	}
-	err := helper.CleanDir(ts.storeDirPath)
-	if err != nil {
-		return err
-	}
+	atomic.StoreInt64(&ts.usedBytes, 0)
 
 	return nil
 }
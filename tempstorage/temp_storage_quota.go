@@ -0,0 +1,67 @@
+package tempstorage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ErrQuotaExceeded is returned when temporary files need more space than quota
+var ErrQuotaExceeded = errors.New("temporary storage quota exceeded")
+
+// SetQuota limits total size of temporary files in bytes, zero means unlimited
+func (ts *TempStorage) SetQuota(quota int64) {
+	ts.quota = quota
+}
+
+// UsedBytes returns total size of temporary files currently stored
+func (ts *TempStorage) UsedBytes() int64 {
+	return atomic.LoadInt64(&ts.usedBytes)
+}
+
+// Err returns the first error happened in background store processes
+func (ts *TempStorage) Err() error {
+	ts.errMutex.Lock()
+	defer ts.errMutex.Unlock()
+	return ts.err
+}
+
+func (ts *TempStorage) setErr(err error) {
+	ts.errMutex.Lock()
+	defer ts.errMutex.Unlock()
+	if ts.err == nil {
+		ts.err = err
+	}
+}
+
+// checkQuota checks whether extra bytes fit in quota
+func (ts *TempStorage) checkQuota(extra int64) error {
+	if ts.quota <= 0 {
+		return nil
+	}
+
+	used := ts.UsedBytes()
+	if used+extra > ts.quota {
+		return fmt.Errorf("%w: %d bytes are used and %d more bytes are needed, but quota is %d bytes",
+			ErrQuotaExceeded, used, extra, ts.quota)
+	}
+	return nil
+}
+
+// quotaWriter counts bytes written to temporary files and fails when quota is exceeded
+type quotaWriter struct {
+	ts *TempStorage
+	w  io.Writer
+}
+
+func (qw quotaWriter) Write(p []byte) (int, error) {
+	if err := qw.ts.checkQuota(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	n, err := qw.w.Write(p)
+	atomic.AddInt64(&qw.ts.usedBytes, int64(n))
+	atomic.AddInt64(&qw.ts.storeBytes, int64(n))
+	return n, err
+}
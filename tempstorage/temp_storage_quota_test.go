@@ -0,0 +1,131 @@
+package tempstorage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTempStorage_Quota(t *testing.T) {
+	ts, err := NewTempStorage("testData", 5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ts.SetQuota(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer func() {
+		cancel()
+		err = ts.Clean()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	err = ts.StoreNextFile(strings.NewReader("Hello\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ts.UsedBytes() != 6 {
+		t.Errorf("used bytes is %d, but should be 6", ts.UsedBytes())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ch, err := ts.GetNextStoreCh(ctx, &wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ch <- []string{"Hello", "Hello"}
+	ch <- []string{"Hello"}
+	close(ch)
+	wg.Wait()
+
+	if !errors.Is(ts.Err(), ErrQuotaExceeded) {
+		t.Errorf("error after exceeding quota is %v, but should be %v", ts.Err(), ErrQuotaExceeded)
+	}
+
+	// Fail fast after a store process is failed
+	_, err = ts.GetNextStoreCh(ctx, &wg)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("error of next store is %v, but should be %v", err, ErrQuotaExceeded)
+	}
+}
+
+func TestTempStorage_QuotaMergeGroup(t *testing.T) {
+	ts, err := NewTempStorage("testData", 5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ts.SetQuota(20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer func() {
+		cancel()
+		err = ts.Clean()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		err = ts.StoreNextFile(strings.NewReader("Hello\n"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	err = ts.SetupNextLevel()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Merging 18 bytes while 18 bytes are stored doesn't fit in quota of 20 bytes,
+	// it fails before any file of the group is opened
+	chs, err := ts.GetNextReadChs(ctx, 3)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("error of merge read is %v, but should be %v", err, ErrQuotaExceeded)
+	}
+	if len(chs) != 0 {
+		t.Errorf("%d read channels are opened, but no one should be", len(chs))
+	}
+
+	ts.SetQuota(40)
+	chs, err = ts.GetNextReadChs(ctx, 3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(chs) != 3 {
+		t.Errorf("%d read channels are opened, but should be 3", len(chs))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	sCh, err := ts.GetNextStoreCh(ctx, &wg)
+	if err != nil {
+		wg.Done()
+		t.Error(err)
+		return
+	}
+	close(sCh)
+	wg.Wait()
+
+	// Consumed files are removed eagerly, before their channels are closed
+	for _, ch := range chs {
+		for range ch {
+		}
+	}
+	if ts.UsedBytes() != 0 {
+		t.Errorf("used bytes after consuming files is %d, but should be 0", ts.UsedBytes())
+	}
+}
@@ -8,13 +8,14 @@ import (
 	"io"
 	"os"
 	"path"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// Read file lines and put in ch one by one
+// Read file lines and put in ch in batches of helper.BatchSize
 // Cleans remove files at end to save storage
-func (ts *TempStorage) fileConsumer(ctx context.Context, parentPath string, info os.FileInfo) (<-chan string, error) {
+func (ts *TempStorage) fileConsumer(ctx context.Context, parentPath string, info os.FileInfo) (<-chan []string, error) {
 	if info.IsDir() {
 		// Their contents will be processed
 		err := fmt.Errorf("no directory should be inside read directory of temporary storage: %s", info.Name())
@@ -33,32 +34,49 @@ func (ts *TempStorage) fileConsumer(ctx context.Context, parentPath string, info
 		return nil, err
 	}
 
-	var ch chan string
-	if ts.chanBuffSize > 1 {
-		ch = make(chan string, ts.chanBuffSize)
+	var ch chan []string
+	if ts.chanBuffSize > 0 {
+		ch = make(chan []string, ts.chanBuffSize)
 	} else {
-		ch = make(chan string)
+		ch = make(chan []string)
 	}
 
 	go func() {
 		defer close(ch)
+		complete := false
 		defer func() {
 			err = file.Close()
 			if err != nil {
 				log.Errorf("error in closing %s: %v", filePath, err)
 				return
 			}
+			if !complete {
+				// File of a stopped reader is removed by Clean, it may be a new file of same run by then
+				return
+			}
 
 			err = os.Remove(filePath)
 			if err != nil {
 				log.Errorf("error in removing %s: %v", filePath, err)
+				return
 			}
+			atomic.AddInt64(&ts.usedBytes, -info.Size())
 		}()
 
 		log.Debugf("Serialize content of %s", filePath)
 
-		reader := bufio.NewReader(file)
+		var reader *bufio.Reader
+		if blockSize := ts.currentIOBlockSize(); blockSize > 0 {
+			readAhead := helper.NewReadAheadReader(file, blockSize)
+			defer func() {
+				_ = readAhead.Close()
+			}()
+			reader = bufio.NewReader(readAhead)
+		} else {
+			reader = bufio.NewReader(file)
+		}
 		var line string
+		batch := make([]string, 0, helper.BatchSize)
 		for {
 			line, err = helper.GetNextLine(reader)
 			if err != nil {
@@ -67,14 +85,29 @@ func (ts *TempStorage) fileConsumer(ctx context.Context, parentPath string, info
 				}
 				log.Errorf("error in reading file %s: %v", filePath, err)
 			}
+			batch = append(batch, line)
+			if len(batch) < helper.BatchSize {
+				continue
+			}
 			select {
 			case <-ctx.Done():
 				log.Warningf("%s reading process is stopped before it finish", filePath)
 				return
 
-			case ch <- line:
+			case ch <- batch:
+				batch = make([]string, 0, helper.BatchSize)
 			}
 		}
+
+		if len(batch) > 0 {
+			select {
+			case <-ctx.Done():
+				log.Warningf("%s reading process is stopped before it finish", filePath)
+				return
+			case ch <- batch:
+			}
+		}
+		complete = true
 	}()
 
 	return ch, nil
@@ -82,30 +115,52 @@ func (ts *TempStorage) fileConsumer(ctx context.Context, parentPath string, info
 
 // GetNextReadChs return read channels for the next up to k available
 // files from read level directory
+// Files are read in order of their run numbers, so groups of merged files are same
+// whatever the file system order of directory entries is
 // ctx is context
 // n is the number of files to read
-// chs is slice of string channels, each element of slice is a channel that will
-// have strings which are lines of a file in read directory
-func (ts *TempStorage) GetNextReadChs(ctx context.Context, n int) (chs []<-chan string, err error) {
-	infos, err := ts.readDirFile.Readdir(n)
-	// Reached the end
-	if err == io.EOF {
-		return nil, nil
+// chs is slice of batch channels, each element of slice is a channel that will
+// have batches of strings which are lines of a file in read directory
+func (ts *TempStorage) GetNextReadChs(ctx context.Context, n int) (chs []<-chan []string, err error) {
+	infos := make([]os.FileInfo, 0, n)
+	var groupSize int64
+	for index := ts.readFileIndex; len(infos) < n && index < ts.readFileCount; index++ {
+		info, statErr := os.Stat(runFilePath(ts.readDirPaths, index))
+		if statErr != nil {
+			log.Errorf("Error in getting info of run %d: %v", index, statErr)
+			return nil, statErr
+		}
+		infos = append(infos, info)
+		groupSize += info.Size()
 	}
-	if err != nil {
-		log.Errorf("Error in Readdir: %v", err)
+
+	// Files being merged are removed only after they are read completely,
+	// so quota is checked before any of them is opened
+	if err = ts.checkQuota(groupSize); err != nil {
 		return nil, err
 	}
 
-	chs = make([]<-chan string, 0, len(infos))
-
+	chs = make([]<-chan []string, 0, len(infos))
+	ts.readGroupSize = groupSize
 	for _, info := range infos {
-		ch, fcErr := ts.fileConsumer(ctx, ts.readDirPath, info)
+		filePath := runFilePath(ts.readDirPaths, ts.readFileIndex)
+		ts.readFileIndex++
+
+		ch, fcErr := ts.fileConsumer(ctx, path.Dir(filePath), info)
 		if fcErr != nil {
 			return nil, fcErr
 		}
 		chs = append(chs, ch)
 	}
 
+	// Reached the end
+	if len(chs) == 0 {
+		return nil, nil
+	}
+
+	if len(chs) > ts.storeFanIn {
+		ts.storeFanIn = len(chs)
+	}
+
 	return chs, nil
 }
@@ -1,11 +1,13 @@
 package tempstorage
 
 import (
+	"AID/solution/helper"
 	"context"
 	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -25,7 +27,7 @@ func TestTempStorage_GetNextReadChs(t *testing.T) {
 	// Write to file manually
 	bytes := []byte("Hello\n")
 	for i := 0; i < numberOfFiles; i++ {
-		filePath := path.Join(ts.storeDirPath, strconv.Itoa(i))
+		filePath := ts.nextStoreFilePath()
 		err = ioutil.WriteFile(filePath, bytes, os.ModePerm)
 		if err != nil {
 			t.Error(err)
@@ -62,14 +64,14 @@ func TestTempStorage_GetNextReadChs(t *testing.T) {
 	for i, ch := range chs {
 		var counter = 0
 		notFinished := true
-		var s string
+		var batch []string
 		for notFinished {
 			select {
 			case <-ctx.Done():
 				t.Errorf("reading from file %d took long time", i)
 				return
-			case s, notFinished = <-ch:
-				if notFinished {
+			case batch, notFinished = <-ch:
+				for _, s := range batch {
 					counter++
 					if s != "Hello" {
 						t.Errorf("line content is \"%s\", but it should be \"Hello\"", s)
@@ -104,7 +106,7 @@ func TestTempStorage_GetNextReadChs2(t *testing.T) {
 	numberOfFiles := 10
 	k := 3
 
-	var ch chan<- string
+	var ch chan<- []string
 	var wg sync.WaitGroup
 	wg.Add(numberOfFiles)
 	for i := 0; i < numberOfFiles; i++ {
@@ -114,7 +116,7 @@ func TestTempStorage_GetNextReadChs2(t *testing.T) {
 			return
 		}
 
-		ch <- "Hello"
+		ch <- []string{"Hello"}
 
 		close(ch)
 	}
@@ -128,7 +130,7 @@ func TestTempStorage_GetNextReadChs2(t *testing.T) {
 	}
 
 	remainingFiles := numberOfFiles
-	var chs []<-chan string
+	var chs []<-chan []string
 	for remainingFiles > 0 {
 		chs, err = ts.GetNextReadChs(ctx, k)
 		if err != nil {
@@ -164,3 +166,140 @@ func TestTempStorage_GetNextReadChs2(t *testing.T) {
 		t.Errorf("len of chs should is %d, but should be zero", len(chs))
 	}
 }
+
+func TestTempStorage_GetNextReadChs_ReadAhead(t *testing.T) {
+	// Own root, so readers left by other tests don't remove its files
+	root := path.Join("testData", "readAhead")
+	if err := helper.MakeCleanDir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	ts, err := NewTempStorage(root, 5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ts.SetIOBlockSize(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer func() {
+		cancel()
+		err = ts.Clean()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	numberOfLines := 1000
+	var lines []string
+	for i := 0; i < numberOfLines; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ch, err := ts.GetNextStoreCh(ctx, &wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ch <- lines
+	close(ch)
+	wg.Wait()
+
+	err = ts.SetupNextLevel()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	chs, err := ts.GetNextReadChs(ctx, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var result []string
+	for batch := range chs[0] {
+		result = append(result, batch...)
+	}
+
+	if len(result) != numberOfLines {
+		t.Errorf("read %d lines, but should be %d", len(result), numberOfLines)
+		return
+	}
+	for i := range result {
+		if result[i] != lines[i] {
+			t.Errorf("line %d is \"%s\", but should be \"%s\"", i, result[i], lines[i])
+			return
+		}
+	}
+}
+
+func TestTempStorage_GetNextReadChs_RunOrder(t *testing.T) {
+	roots := []string{path.Join("testData", "order1"), path.Join("testData", "order2")}
+	for _, root := range roots {
+		if err := helper.MakeCleanDir(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for _, root := range roots {
+			_ = os.RemoveAll(root)
+		}
+	}()
+
+	ts, err := NewStripedTempStorage(roots, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	numberOfFiles := 12
+	for i := 0; i < numberOfFiles; i++ {
+		err = ts.StoreNextFile(strings.NewReader(strconv.Itoa(i) + "\n"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	err = ts.SetupNextLevel()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Files are grouped in order of run numbers
+	run := 0
+	for {
+		chs, err := ts.GetNextReadChs(ctx, 5)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(chs) == 0 {
+			break
+		}
+		for _, ch := range chs {
+			var lines []string
+			for batch := range ch {
+				lines = append(lines, batch...)
+			}
+			if len(lines) != 1 || lines[0] != strconv.Itoa(run) {
+				t.Errorf("run %d has content %v", run, lines)
+			}
+			run++
+		}
+	}
+
+	if run != numberOfFiles {
+		t.Errorf("%d files are read, but should be %d", run, numberOfFiles)
+	}
+}
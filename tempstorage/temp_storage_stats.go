@@ -0,0 +1,30 @@
+package tempstorage
+
+import "sync/atomic"
+
+// LevelStats summarizes files stored at a level of TempStorage
+type LevelStats struct {
+	Level int   `json:"level"`
+	Files int   `json:"files"`  // number of files stored at level
+	FanIn int   `json:"fan_in"` // largest number of files merged into a file of level, zero for files not merged from previous level
+	Bytes int64 `json:"bytes"`  // bytes written to files of level
+}
+
+// Levels returns stats of levels stored so far, the store level is included if it has files
+func (ts *TempStorage) Levels() []LevelStats {
+	levels := append([]LevelStats(nil), ts.levels...)
+	if ts.storeFileCounter > 0 {
+		levels = append(levels, ts.storeLevelStats())
+	}
+	return levels
+}
+
+// storeLevelStats returns stats of store level
+func (ts *TempStorage) storeLevelStats() LevelStats {
+	return LevelStats{
+		Level: ts.storeLevel,
+		Files: ts.storeFileCounter,
+		FanIn: ts.storeFanIn,
+		Bytes: atomic.LoadInt64(&ts.storeBytes),
+	}
+}
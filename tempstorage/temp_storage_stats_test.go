@@ -0,0 +1,78 @@
+package tempstorage
+
+import (
+	"AID/solution/helper"
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTempStorage_Levels(t *testing.T) {
+	root := path.Join("testData", "levels")
+	if err := helper.MakeCleanDir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(root)
+	}()
+
+	ts, err := NewTempStorage(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		err = ts.StoreNextFile(strings.NewReader("line\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []LevelStats{{Level: 0, Files: 5, Bytes: 25}}
+	if levels := ts.Levels(); !reflect.DeepEqual(levels, expected) {
+		t.Errorf("levels are %+v, but should be %+v", levels, expected)
+	}
+
+	err = ts.SetupNextLevel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Store level without files is not included
+	if levels := ts.Levels(); !reflect.DeepEqual(levels, expected) {
+		t.Errorf("levels are %+v, but should be %+v", levels, expected)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Files are merged 3 and 2 into the next level
+	for {
+		chs, err := ts.GetNextReadChs(ctx, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chs) == 0 {
+			break
+		}
+		var content strings.Builder
+		for _, ch := range chs {
+			for batch := range ch {
+				for _, s := range batch {
+					content.WriteString(s + "\n")
+				}
+			}
+		}
+		err = ts.StoreNextFile(strings.NewReader(content.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected = append(expected, LevelStats{Level: 1, Files: 2, FanIn: 3, Bytes: 25})
+	if levels := ts.Levels(); !reflect.DeepEqual(levels, expected) {
+		t.Errorf("levels are %+v, but should be %+v", levels, expected)
+	}
+}
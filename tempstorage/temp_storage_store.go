@@ -3,6 +3,7 @@ package tempstorage
 import (
 	"bufio"
 	"context"
+	"io"
 	"os"
 	"path"
 	"strconv"
@@ -11,52 +12,129 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// GetNextStoreCh return write channel for next file in store level directory
-// strings are put in chan will be written as lines in the file
-// caller is responsible for closing the chan, after that file writer is closed too
-func (ts *TempStorage) GetNextStoreCh(ctx context.Context, wg *sync.WaitGroup) (chan<- string, error) {
-	fileName := strconv.Itoa(ts.storeFileCounter)
-	ts.storeFileCounter++
+// newWriter creates buffered writer of file with size of I/O block, written bytes are counted in quota
+// I/O block is shrunk under memory pressure
+func (ts *TempStorage) newWriter(file *os.File) *bufio.Writer {
+	qw := quotaWriter{ts: ts, w: file}
+	if blockSize := ts.currentIOBlockSize(); blockSize > 0 {
+		return bufio.NewWriterSize(qw, blockSize)
+	}
+	return bufio.NewWriter(qw)
+}
 
-	filePath := path.Join(ts.storeDirPath, fileName)
+// createNextStoreFile creates next file in store level directories
+// It fails fast if a store process has already failed or the file to be merged doesn't fit in quota
+func (ts *TempStorage) createNextStoreFile() (*os.File, string, error) {
+	if err := ts.Err(); err != nil {
+		return nil, "", err
+	}
+
+	// Files being merged are removed only after they are read completely
+	if err := ts.checkQuota(ts.readGroupSize); err != nil {
+		return nil, "", err
+	}
 
+	filePath := ts.nextStoreFilePath()
 	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, filePath, nil
+}
+
+// runFilePath returns path of file with run number in level directories,
+// files are distributed across root directories round-robin
+func runFilePath(levelDirPaths []string, run int) string {
+	return path.Join(levelDirPaths[run%len(levelDirPaths)], strconv.Itoa(run))
+}
+
+// nextStoreFilePath generates path of next file in store level directories
+func (ts *TempStorage) nextStoreFilePath() string {
+	filePath := runFilePath(ts.storeDirPaths, ts.storeFileCounter)
+	ts.storeFileCounter++
+
+	return filePath
+}
+
+// StoreNextFile writes run to next file in store level directory synchronously
+// run is expected to write lines, e.g. bundler.RunBuffer
+func (ts *TempStorage) StoreNextFile(run io.WriterTo) (err error) {
+	file, filePath, err := ts.createNextStoreFile()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	writer := ts.newWriter(file)
+	_, err = run.WriteTo(writer)
+	if err != nil {
+		log.Errorf("error in writing to %s: %v", filePath, err)
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// GetNextStoreCh return write channel for next file in store level directory
+// strings of batches are put in chan will be written as lines in the file
+// caller is responsible for closing the chan, after that file writer is closed too
+// write errors stop writing the file and are reported by Err, the chan is still drained
+func (ts *TempStorage) GetNextStoreCh(ctx context.Context, wg *sync.WaitGroup) (chan<- []string, error) {
+	file, filePath, err := ts.createNextStoreFile()
 	if err != nil {
 		return nil, err
 	}
 
-	var ch chan string
-	if ts.chanBuffSize > 1 {
-		ch = make(chan string, ts.chanBuffSize)
+	var ch chan []string
+	if ts.chanBuffSize > 0 {
+		ch = make(chan []string, ts.chanBuffSize)
 	} else {
-		ch = make(chan string)
+		ch = make(chan []string)
 	}
 
-	go func(ch <-chan string, file *os.File) {
+	go func(ch <-chan []string, file *os.File) {
 		defer wg.Done()
 
-		writer := bufio.NewWriter(file)
+		writer := ts.newWriter(file)
+		var writeErr error
 
 		for {
 			select {
-			case s, ok := <-ch:
+			case batch, ok := <-ch:
 				if !ok {
-					err = writer.Flush()
-					if err != nil {
-						log.Errorf("error on flushing data on %s: %v", filePath, err)
+					if writeErr == nil {
+						err = writer.Flush()
+						if err != nil {
+							log.Errorf("error on flushing data on %s: %v", filePath, err)
+							ts.setErr(err)
+						}
 					}
 					err = file.Close()
 					if err != nil {
 						log.Errorf("error in closing %s: %v", filePath, err)
+						ts.setErr(err)
 					}
 					return
 				}
-				_, err = writer.WriteString(s)
-				if err == nil {
-					err = writer.WriteByte('\n')
-				}
-				if err != nil {
-					log.Errorf("error in writing to %s: %v", filePath, err)
+				// After a failure rest of data is drained to not block the sender
+				for _, s := range batch {
+					if writeErr != nil {
+						break
+					}
+					_, writeErr = writer.WriteString(s)
+					if writeErr == nil {
+						writeErr = writer.WriteByte('\n')
+					}
+					if writeErr != nil {
+						log.Errorf("error in writing to %s: %v", filePath, writeErr)
+						ts.setErr(writeErr)
+					}
 				}
 
 			case <-ctx.Done():
@@ -2,6 +2,9 @@ package tempstorage
 
 import (
 	"context"
+	"io/ioutil"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -36,7 +39,7 @@ func TestTempStorage_GetNextStoreCh(t *testing.T) {
 
 	for i := 0; i < numberOfLines; i++ {
 		select {
-		case ch <- "Hello":
+		case ch <- []string{"Hello"}:
 		case <-ctx.Done():
 			t.Error("write to file took long time")
 			return
@@ -66,14 +69,14 @@ func TestTempStorage_GetNextStoreCh(t *testing.T) {
 	for i, ch := range chs {
 		var counter = 0
 		notFinished := true
-		var s string
+		var batch []string
 		for notFinished {
 			select {
 			case <-ctx.Done():
 				t.Errorf("reading from file %d took long time", i)
 				return
-			case s, notFinished = <-ch:
-				if notFinished {
+			case batch, notFinished = <-ch:
+				for _, s := range batch {
 					counter++
 					if s != "Hello" {
 						t.Errorf("line content is \"%s\", but it should be \"Hello\"", s)
@@ -87,3 +90,83 @@ func TestTempStorage_GetNextStoreCh(t *testing.T) {
 		}
 	}
 }
+
+func benchmarkStoreCh(b *testing.B, batchSize int) {
+	ts, err := NewTempStorage("testData", 5)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		err = ts.Clean()
+		if err != nil {
+			b.Error(err)
+		}
+	}()
+
+	numberOfLines := 100000
+	lines := make([]string, numberOfLines)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i)
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		ch, err := ts.GetNextStoreCh(ctx, &wg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < numberOfLines; j += batchSize {
+			ch <- lines[j : j+batchSize]
+		}
+		close(ch)
+		wg.Wait()
+	}
+}
+
+// BenchmarkTempStorage_GetNextStoreCh_SingleLine moves one line per channel operation
+func BenchmarkTempStorage_GetNextStoreCh_SingleLine(b *testing.B) {
+	benchmarkStoreCh(b, 1)
+}
+
+// BenchmarkTempStorage_GetNextStoreCh_Batch moves 1000 lines per channel operation
+func BenchmarkTempStorage_GetNextStoreCh_Batch(b *testing.B) {
+	benchmarkStoreCh(b, 1000)
+}
+
+func TestTempStorage_StoreNextFile(t *testing.T) {
+	ts, err := NewTempStorage("testData", 5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		err = ts.Clean()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	err = ts.StoreNextFile(strings.NewReader("Hello\nHello\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	hasSingle, filePath := ts.HasSingleStoredFile()
+	if !hasSingle {
+		t.Error("one file should be stored")
+		return
+	}
+
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(content) != "Hello\nHello\n" {
+		t.Errorf("stored content is %q, but should be %q", content, "Hello\nHello\n")
+	}
+}
@@ -2,9 +2,14 @@ package tempstorage
 
 import (
 	"AID/solution/helper"
+	"context"
 	"os"
 	"path"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetTempLevelPath(t *testing.T) {
@@ -19,19 +24,19 @@ func TestGetTempLevelPath(t *testing.T) {
 			t.Error(err)
 		}
 	}()
-	_, err = ts.getTempLevelPath(-1)
+	_, err = ts.getTempLevelPath(ts.paths[0], -1)
 	if err == nil {
 		t.Error("getTempLevelPath does not return error on negative input")
 		return
 	}
 
-	_, err = ts.getTempLevelPath(-13232)
+	_, err = ts.getTempLevelPath(ts.paths[0], -13232)
 	if err == nil {
 		t.Error("getTempLevelPath does not return error on negative input")
 		return
 	}
 
-	tempPath, err := ts.getTempLevelPath(0)
+	tempPath, err := ts.getTempLevelPath(ts.paths[0], 0)
 	if err != nil {
 		t.Errorf("getTempLevelPath returnx error on valid input: %v", err)
 		return
@@ -42,7 +47,7 @@ func TestGetTempLevelPath(t *testing.T) {
 		t.Errorf("getTempLevelPath returns %s, expected %s", tempPath, expectedPath)
 	}
 
-	tempPath, err = ts.getTempLevelPath(322)
+	tempPath, err = ts.getTempLevelPath(ts.paths[0], 322)
 	if err != nil {
 		t.Errorf("getTempLevelPath returnx error on valid input: %v", err)
 		return
@@ -184,3 +189,126 @@ func TestTempStorage_SetupNextLevel(t *testing.T) {
 		t.Errorf("Level two dir %s should exists and writable", levelTwoDir)
 	}
 }
+
+func TestIOBlockSizeFromBudget(t *testing.T) {
+	if size := IOBlockSizeFromBudget(1<<28, 5000); size != MinIOBlockSize {
+		t.Errorf("block size is %d, but should be clamped to %d", size, MinIOBlockSize)
+	}
+	if size := IOBlockSizeFromBudget(1<<40, 2); size != MaxIOBlockSize {
+		t.Errorf("block size is %d, but should be clamped to %d", size, MaxIOBlockSize)
+	}
+	if size := IOBlockSizeFromBudget(5<<20, 2); size != 1<<20 {
+		t.Errorf("block size is %d, but should be %d", size, 1<<20)
+	}
+}
+
+func TestNewStripedTempStorage(t *testing.T) {
+	_, err := NewStripedTempStorage(nil, 1)
+	if err == nil {
+		t.Error("NewStripedTempStorage should return error on empty paths")
+	}
+
+	_, err = NewStripedTempStorage([]string{"testData", "notExistsDir"}, 1)
+	if err == nil {
+		t.Error("NewStripedTempStorage should return error if one of paths is invalid")
+	}
+
+	roots := []string{path.Join("testData", "disk1"), path.Join("testData", "disk2")}
+	for _, root := range roots {
+		err = helper.MakeCleanDir(root)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	defer func() {
+		for _, root := range roots {
+			err = helper.CleanDir(root)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	ts, err := NewStripedTempStorage(roots, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		err = ts.Clean()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	numberOfFiles := 5
+	for i := 0; i < numberOfFiles; i++ {
+		err = ts.StoreNextFile(strings.NewReader("Hello\n"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// Files are distributed round-robin
+	for i := 0; i < numberOfFiles; i++ {
+		filePath := path.Join(roots[i%len(roots)], "0", strconv.Itoa(i))
+		if _, err = os.Stat(filePath); err != nil {
+			t.Errorf("file %d should be stored at %s: %v", i, filePath, err)
+		}
+	}
+
+	err = ts.SetupNextLevel()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var counts []int
+	for {
+		chs, err := ts.GetNextReadChs(ctx, 3)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(chs) == 0 {
+			break
+		}
+		counts = append(counts, len(chs))
+		for _, ch := range chs {
+			for range ch {
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(counts, []int{3, 2}) {
+		t.Errorf("files are read in groups of %v, but should be [3 2]", counts)
+	}
+}
+
+func TestTempStorage_currentIOBlockSize(t *testing.T) {
+	ts := &TempStorage{}
+	if size := ts.currentIOBlockSize(); size != 0 {
+		t.Errorf("default buffers should be kept, got %d", size)
+	}
+
+	pressure := false
+	ts.SetIOBlockSize(MaxIOBlockSize)
+	ts.SetPressureFunc(func() bool { return pressure })
+	if size := ts.currentIOBlockSize(); size != MaxIOBlockSize {
+		t.Errorf("block size without pressure is %d", size)
+	}
+
+	pressure = true
+	if size := ts.currentIOBlockSize(); size != MaxIOBlockSize/pressureIOBlockDivisor {
+		t.Errorf("block size under pressure is %d", size)
+	}
+	ts.SetIOBlockSize(MinIOBlockSize)
+	if size := ts.currentIOBlockSize(); size != MinIOBlockSize {
+		t.Errorf("block size should not be shrunk below minimum, got %d", size)
+	}
+}